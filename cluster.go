@@ -0,0 +1,266 @@
+package dali
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/mibk/dali/dialect"
+)
+
+// Cluster is a DB that splits reads and writes between a primary
+// database and its replicas. Queries executed outside transactions
+// using the Rows, ScanRow, ScanAllRows, One, or All methods of Query
+// are sent to the replicas in a round-robin fashion. Queries executed
+// using Exec, transactions, and prepared statements use the primary.
+// To read from the primary (e.g. to see a just written row), use
+// the context returned by UsePrimary.
+//
+// Replicas failing health checks (see CheckHealth) are not used until
+// they pass again. If no replica is healthy, the primary is used instead.
+type Cluster struct {
+	*DB
+	replicas []*sql.DB
+	healthy  []atomic.Bool
+	next     atomic.Uint32
+}
+
+// NewCluster instantiates Cluster from the given database/sql DB handles
+// of the primary and replicas in the particular dialect. If there are
+// no replicas, all queries are sent to the primary.
+func NewCluster(primary *sql.DB, d dialect.Dialect, replicas ...*sql.DB) *Cluster {
+	c := &Cluster{
+		DB:       NewDB(primary, d),
+		replicas: replicas,
+		healthy:  make([]atomic.Bool, len(replicas)),
+	}
+	for i := range c.healthy {
+		c.healthy[i].Store(true)
+	}
+	c.DB.execer = clusterExecer{c}
+	return c
+}
+
+// Close closes the primary and all replicas, releasing any open
+// resources. It returns the first error encountered.
+func (c *Cluster) Close() error {
+	err := c.DB.Close()
+	for _, r := range c.replicas {
+		if rerr := r.Close(); err == nil {
+			err = rerr
+		}
+	}
+	return err
+}
+
+// Ping verifies connections to the primary and all replicas are still
+// alive, establishing connections if necessary. It returns the first
+// error encountered.
+func (c *Cluster) Ping() error {
+	if err := c.DB.Ping(); err != nil {
+		return err
+	}
+	for _, r := range c.replicas {
+		if err := r.Ping(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reader returns the database handle for reading with ctx.
+func (c *Cluster) reader(ctx context.Context) *sql.DB {
+	if len(c.replicas) == 0 || usesPrimary(ctx) {
+		return c.DB.DB
+	}
+	// The modulo is computed on the counter before converting it to int,
+	// which may be 32-bit, so that it isn't negative after the counter
+	// exceeds math.MaxInt32.
+	n := int((c.next.Add(1) - 1) % uint32(len(c.replicas)))
+	for i := range c.replicas {
+		j := (n + i) % len(c.replicas)
+		if c.healthy[j].Load() {
+			return c.replicas[j]
+		}
+	}
+	return c.DB.DB
+}
+
+// HealthCheck configures health checking of replicas.
+type HealthCheck struct {
+	// Interval is the time between two checks performed
+	// by StartHealthCheck. Defaults to 5 seconds.
+	Interval time.Duration
+
+	// Timeout limits the duration of checking a single replica.
+	// Defaults to Interval.
+	Timeout time.Duration
+
+	// MaxLag is the maximum replication lag of a healthy replica.
+	// If zero, the lag is not checked. The lag is queried using the
+	// statement provided by the dialect, which must implement
+	// dialect.ReplicationLagQuerier, unless LagQuery is set.
+	MaxLag time.Duration
+
+	// LagQuery and LagColumn, if set, override the statement
+	// reporting the replication status (a dali template) and
+	// the name of the column holding the lag in seconds. E.g.,
+	// MySQL older than 8.0.22 requires "SHOW SLAVE STATUS" and
+	// "Seconds_Behind_Master".
+	LagQuery  string
+	LagColumn string
+
+	// OnChange, if set, is called whenever a replica changes its state.
+	// The replica is identified by its index in the list passed to
+	// NewCluster; err is the reason it is unhealthy, or nil.
+	OnChange func(replica int, err error)
+}
+
+func (hc HealthCheck) withDefaults() HealthCheck {
+	if hc.Interval <= 0 {
+		hc.Interval = 5 * time.Second
+	}
+	if hc.Timeout <= 0 {
+		hc.Timeout = hc.Interval
+	}
+	return hc
+}
+
+// StartHealthCheck checks the health of replicas periodically, as
+// configured by hc, in a separate goroutine until ctx is canceled.
+// The first check is performed before StartHealthCheck returns.
+func (c *Cluster) StartHealthCheck(ctx context.Context, hc HealthCheck) error {
+	hc = hc.withDefaults()
+	if err := c.CheckHealth(ctx, hc); err != nil {
+		return err
+	}
+	go func() {
+		t := time.NewTicker(hc.Interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				c.CheckHealth(ctx, hc)
+			}
+		}
+	}()
+	return nil
+}
+
+// CheckHealth pings all replicas and checks their replication lag, as
+// configured by hc. Replicas that fail are ejected until they pass
+// a subsequent check. The returned error only reports an invalid
+// configuration.
+func (c *Cluster) CheckHealth(ctx context.Context, hc HealthCheck) error {
+	hc = hc.withDefaults()
+	query, col := hc.LagQuery, hc.LagColumn
+	if lq, ok := c.dialect.(dialect.ReplicationLagQuerier); ok {
+		q, c := lq.ReplicationLagQuery()
+		if query == "" {
+			query = q
+		}
+		if col == "" {
+			col = c
+		}
+	}
+	if hc.MaxLag > 0 && (query == "" || col == "") {
+		return errors.New("dali: dialect cannot report replication lag")
+	}
+	for i, r := range c.replicas {
+		ctx, cancel := context.WithTimeout(ctx, hc.Timeout)
+		err := r.PingContext(ctx)
+		if err == nil && hc.MaxLag > 0 {
+			err = checkLag(ctx, r, c.dialect, query, col, hc.MaxLag)
+		}
+		cancel()
+		if healthy := err == nil; c.healthy[i].Swap(healthy) != healthy && hc.OnChange != nil {
+			hc.OnChange(i, err)
+		}
+	}
+	return nil
+}
+
+func checkLag(ctx context.Context, db *sql.DB, d dialect.Dialect, query, col string, max time.Duration) error {
+	query, err := translate(d, query, nil)
+	if err != nil {
+		return err
+	}
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	vals := make([]interface{}, len(cols))
+	index := -1
+	var lag sql.NullString
+	for i, c := range cols {
+		vals[i] = new(sql.RawBytes)
+		if c == col {
+			index = i
+			vals[i] = &lag
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("dali: column %s missing in replication status", col)
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return errors.New("dali: replication not configured")
+	}
+	if err := rows.Scan(vals...); err != nil {
+		return err
+	}
+	if !lag.Valid {
+		return errors.New("dali: replication not running")
+	}
+	sec, err := strconv.ParseFloat(lag.String, 64)
+	if err != nil {
+		return fmt.Errorf("dali: parse replication lag: %v", err)
+	}
+	if d := time.Duration(sec * float64(time.Second)); d > max {
+		return fmt.Errorf("dali: replication lag %v exceeds %v", d, max)
+	}
+	return nil
+}
+
+type primaryKey struct{}
+
+// UsePrimary returns a copy of ctx that makes Cluster send
+// all queries executed with it to the primary.
+func UsePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+func usesPrimary(ctx context.Context) bool {
+	v, _ := ctx.Value(primaryKey{}).(bool)
+	return v
+}
+
+// clusterExecer routes queries between the primary and replicas.
+type clusterExecer struct {
+	c *Cluster
+}
+
+func (e clusterExecer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return e.c.DB.DB.ExecContext(ctx, query, args...)
+}
+
+func (e clusterExecer) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return e.c.reader(ctx).QueryContext(ctx, query, args...)
+}
+
+func (e clusterExecer) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return e.c.reader(ctx).QueryRowContext(ctx, query, args...)
+}
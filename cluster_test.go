@@ -0,0 +1,164 @@
+package dali
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mibk/dali/internal/testdriver"
+)
+
+func TestCluster(t *testing.T) {
+	primary, primaryHandle := testdriver.New()
+	replica1, replica1Handle := testdriver.New()
+	replica2, replica2Handle := testdriver.New()
+	c := NewCluster(primaryHandle, FakeDialect{}, replica1Handle, replica2Handle)
+
+	var n int
+	c.Query("SELECT 1").ScanRow(&n)
+	c.Query("SELECT 2").Rows()
+	c.Query("SELECT 3").All(&[]U{})
+	c.Query("UPDATE 4").Exec()
+	c.QueryWithContext(UsePrimary(context.Background()), "SELECT 5").Rows()
+	tx, _ := c.Begin()
+	tx.Query("SELECT 6").Rows()
+	stmt, _ := c.Prepare("SELECT 7")
+	stmt.Bind().Rows()
+
+	tests := []struct {
+		name string
+		drv  *testdriver.Driver
+		want []string
+	}{
+		{"primary", primary, []string{"UPDATE 4", "SELECT 5", "SELECT 6", "SELECT 7"}},
+		{"replica1", replica1, []string{"SELECT 1", "SELECT 3"}},
+		{"replica2", replica2, []string{"SELECT 2"}},
+	}
+	for _, tt := range tests {
+		if got := tt.drv.Queries(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got: %q\nwant: %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClusterReaderOverflow(t *testing.T) {
+	_, primaryHandle := testdriver.New()
+	_, replica1Handle := testdriver.New()
+	_, replica2Handle := testdriver.New()
+	_, replica3Handle := testdriver.New()
+	c := NewCluster(primaryHandle, FakeDialect{}, replica1Handle, replica2Handle, replica3Handle)
+
+	c.next.Store(math.MaxInt32)
+	var got []*sql.DB
+	for i := 0; i < 3; i++ {
+		got = append(got, c.reader(context.Background()))
+	}
+	// math.MaxInt32 % 3 == 1
+	if want := []*sql.DB{replica2Handle, replica3Handle, replica1Handle}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %p, want %p", got, want)
+	}
+
+	c.next.Store(math.MaxUint32)
+	for i := 0; i < 3; i++ {
+		if db := c.reader(context.Background()); db == primaryHandle {
+			t.Errorf("#%d: primary used for reading", i)
+		}
+	}
+}
+
+func TestClusterWithoutReplicas(t *testing.T) {
+	primary, primaryHandle := testdriver.New()
+	c := NewCluster(primaryHandle, FakeDialect{})
+	c.Query("SELECT 1").Rows()
+	if got, want := primary.Queries(), []string{"SELECT 1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+type lagDialect struct{ FakeDialect }
+
+func (lagDialect) ReplicationLagQuery() (query, column string) { return "SHOW LAG", "Lag" }
+
+func TestClusterHealthCheck(t *testing.T) {
+	primary, primaryHandle := testdriver.New()
+	replica1, replica1Handle := testdriver.New()
+	replica2, replica2Handle := testdriver.New()
+	c := NewCluster(primaryHandle, lagDialect{}, replica1Handle, replica2Handle)
+
+	type change struct {
+		replica int
+		healthy bool
+	}
+	var changes []change
+	hc := HealthCheck{
+		MaxLag: 10 * time.Second,
+		OnChange: func(replica int, err error) {
+			changes = append(changes, change{replica, err == nil})
+		},
+	}
+	lagCols := []string{"Host", "Lag"}
+	check := func(want ...change) {
+		t.Helper()
+		changes = nil
+		if err := c.CheckHealth(context.Background(), hc); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(changes, want) {
+			t.Errorf("changes: got %v, want %v", changes, want)
+		}
+		primary.Queries()
+		replica1.Queries()
+		replica2.Queries()
+	}
+	read := func() (p, r1, r2 int) {
+		t.Helper()
+		for i := 0; i < 4; i++ {
+			c.Query("SELECT 1").Rows()
+		}
+		return len(primary.Queries()), len(replica1.Queries()), len(replica2.Queries())
+	}
+
+	replica1.SetRows(lagCols, []driver.Value{"r1", int64(2)})
+	replica2.SetRows(lagCols, []driver.Value{"r2", "30.5"})
+	check(change{1, false})
+	if p, r1, r2 := read(); p != 0 || r1 != 4 || r2 != 0 {
+		t.Errorf("lagging replica: got %d, %d, %d queries", p, r1, r2)
+	}
+
+	replica1.SetErr(errors.New("connection refused"))
+	check(change{0, false})
+	if p, r1, r2 := read(); p != 4 || r1 != 0 || r2 != 0 {
+		t.Errorf("no healthy replica: got %d, %d, %d queries", p, r1, r2)
+	}
+
+	replica1.SetErr(nil)
+	replica2.SetRows(lagCols, []driver.Value{"r2", int64(0)})
+	check(change{0, true}, change{1, true})
+	if p, r1, r2 := read(); p != 0 || r1 != 2 || r2 != 2 {
+		t.Errorf("healthy replicas: got %d, %d, %d queries", p, r1, r2)
+	}
+
+	replica2.SetRows(lagCols, []driver.Value{"r2", nil})
+	check(change{1, false})
+	replica2.SetRows(lagCols)
+	check()
+
+	c = NewCluster(primaryHandle, FakeDialect{}, replica1Handle)
+	if err := c.CheckHealth(context.Background(), hc); err == nil {
+		t.Errorf("lag check with unsupported dialect: an error was expected but none given")
+	}
+
+	// The statement can be configured, e.g. for older servers.
+	hc.LagQuery, hc.LagColumn = "SHOW [old] LAG", "OldLag"
+	replica1.SetRows([]string{"OldLag"}, []driver.Value{int64(20)})
+	check(change{0, false})
+	c.CheckHealth(context.Background(), hc)
+	if got, want := replica1.Queries(), []string{"SHOW {old} LAG"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
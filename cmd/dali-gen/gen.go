@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	pathpkg "path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mibk/dali/internal/placeholder"
+	"github.com/mibk/dali/internal/sqlfile"
+)
+
+// A query is an annotated query read from an SQL file:
+//
+//	-- name: FindUsersByGroup :all User
+//	-- params: groupID int64
+//	SELECT * FROM [user] WHERE [group_id] = ?
+type query struct {
+	pos    string // file:line of the header
+	Name   string
+	Kind   string // one, all, or exec
+	Type   string // result type for one and all
+	Params []param
+	Doc    []string // leading comments
+	SQL    string
+}
+
+type param struct {
+	Name, Type string
+}
+
+var (
+	nameRx   = regexp.MustCompile(`^--\s*name:`)
+	headerRx = regexp.MustCompile(`^--\s*name:\s*(\S+)\s+:(\w+)(?:\s+(\S+))?\s*$`)
+	paramsRx = regexp.MustCompile(`^--\s*params:(.*)$`)
+)
+
+// parseQueries parses the annotated queries in src read from file.
+func parseQueries(file, src string) ([]*query, error) {
+	var (
+		queries []*query
+		q       *query
+		body    []string
+	)
+	finish := func() error {
+		if q == nil {
+			return nil
+		}
+		q.SQL = sqlfile.TrimQuery(strings.Join(body, "\n"))
+		if q.SQL == "" {
+			return fmt.Errorf("%s: empty query %s", q.pos, q.Name)
+		}
+		phs, err := placeholder.Parse(q.SQL)
+		if err != nil {
+			return fmt.Errorf("%s: query %s: %v", q.pos, q.Name, err)
+		}
+		if len(phs) != len(q.Params) {
+			return fmt.Errorf("%s: query %s has %d placeholders, but %d params", q.pos, q.Name, len(phs), len(q.Params))
+		}
+		queries = append(queries, q)
+		return nil
+	}
+	for i, line := range strings.Split(src, "\n") {
+		line = strings.TrimRight(line, "\r")
+		pos := fmt.Sprintf("%s:%d", file, i+1)
+		if nameRx.MatchString(line) {
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			var err error
+			if q, err = parseHeader(pos, line); err != nil {
+				return nil, err
+			}
+			body = nil
+			continue
+		}
+		if q == nil {
+			if s := strings.TrimSpace(line); s != "" && !strings.HasPrefix(s, "--") {
+				return nil, fmt.Errorf("%s: query without a name header", pos)
+			}
+			continue
+		}
+		if m := paramsRx.FindStringSubmatch(line); m != nil && len(body) == 0 {
+			if q.Params != nil {
+				return nil, fmt.Errorf("%s: duplicate params of %s", pos, q.Name)
+			}
+			var err error
+			if q.Params, err = parseParams(m[1]); err != nil {
+				return nil, fmt.Errorf("%s: %v", pos, err)
+			}
+			continue
+		}
+		if s := strings.TrimSpace(line); strings.HasPrefix(s, "--") && len(body) == 0 {
+			q.Doc = append(q.Doc, strings.TrimSpace(strings.TrimPrefix(s, "--")))
+			continue
+		}
+		body = append(body, line)
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	return queries, nil
+}
+
+func parseHeader(pos, line string) (*query, error) {
+	m := headerRx.FindStringSubmatch(line)
+	if m == nil {
+		return nil, fmt.Errorf("%s: malformed header, want -- name: Name :kind [Type]", pos)
+	}
+	q := &query{pos: pos, Name: m[1], Kind: m[2], Type: m[3]}
+	if !token.IsIdentifier(q.Name) {
+		return nil, fmt.Errorf("%s: invalid query name %s", pos, q.Name)
+	}
+	switch q.Kind {
+	case "one", "all":
+		if q.Type == "" {
+			return nil, fmt.Errorf("%s: missing result type of :%s query %s", pos, q.Kind, q.Name)
+		}
+		if _, err := parser.ParseExpr(q.Type); err != nil {
+			return nil, fmt.Errorf("%s: invalid result type %s", pos, q.Type)
+		}
+	case "exec":
+		if q.Type != "" {
+			return nil, fmt.Errorf("%s: unexpected result type of :exec query %s", pos, q.Name)
+		}
+	default:
+		return nil, fmt.Errorf("%s: unknown query kind :%s (want :one, :all, or :exec)", pos, q.Kind)
+	}
+	return q, nil
+}
+
+// parseParams parses a parameter list, such as "id int64, names []string".
+func parseParams(list string) ([]param, error) {
+	expr, err := parser.ParseExpr("func(" + list + ")")
+	if err != nil {
+		return nil, fmt.Errorf("invalid params: %s", strings.TrimSpace(list))
+	}
+	params := []param{}
+	for _, f := range expr.(*ast.FuncType).Params.List {
+		if len(f.Names) == 0 {
+			return nil, fmt.Errorf("unnamed param of type %s", types.ExprString(f.Type))
+		}
+		for _, name := range f.Names {
+			switch name.Name {
+			case "ctx", "q", "v", "err", "_":
+				return nil, fmt.Errorf("reserved param name %s", name.Name)
+			}
+			params = append(params, param{name.Name, types.ExprString(f.Type)})
+		}
+	}
+	return params, nil
+}
+
+// generate returns the source of the package pkg with functions
+// executing the queries. The imports map the names of the packages
+// used by the params and result types to their paths.
+func generate(pkg string, queries []*query, imports map[string]string) ([]byte, error) {
+	used := map[string]string{"context": generated["context"], "dali": generated["dali"]}
+	for _, q := range queries {
+		if q.Kind == "exec" {
+			used["sql"] = generated["sql"]
+		}
+	}
+	for name, path := range imports {
+		used[name] = path
+	}
+	var std, other []string
+	for name, path := range used {
+		spec := strconv.Quote(path)
+		if pathpkg.Base(path) != name {
+			spec = name + " " + spec
+		}
+		if first, _, _ := strings.Cut(path, "/"); strings.Contains(first, ".") {
+			other = append(other, spec)
+		} else {
+			std = append(std, spec)
+		}
+	}
+	sort.Slice(std, func(i, j int) bool { return importPath(std[i]) < importPath(std[j]) })
+	sort.Slice(other, func(i, j int) bool { return importPath(other[i]) < importPath(other[j]) })
+
+	var buf bytes.Buffer
+	err := genTemplate.Execute(&buf, struct {
+		Package    string
+		Std, Other []string
+		Queries    []*query
+	}{pkg, std, other, queries})
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+// importPath returns the path of an import spec.
+func importPath(spec string) string {
+	return spec[strings.IndexByte(spec, '"'):]
+}
+
+var genTemplate = template.Must(template.New("").Funcs(template.FuncMap{
+	"const": func(name string) string {
+		r, n := utf8.DecodeRuneInString(name)
+		return string(unicode.ToLower(r)) + name[n:] + "SQL"
+	},
+	"quote": func(s string) string {
+		if strings.Contains(s, "`") {
+			return strconv.Quote(s)
+		}
+		return "`" + s + "`"
+	},
+}).Parse(`// Code generated by dali-gen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- range .Std}}
+	{{.}}
+{{- end}}
+{{range .Other}}
+	{{.}}
+{{- end}}
+)
+
+{{range .Queries}}
+const {{const .Name}} = {{quote .SQL}}
+{{range .Doc}}
+// {{.}}{{end}}
+func {{.Name}}(ctx context.Context, q dali.Querier{{range .Params}}, {{.Name}} {{.Type}}{{end}}) (
+{{- if eq .Kind "one"}}{{.Type}}{{else if eq .Kind "all"}}[]{{.Type}}{{else}}sql.Result{{end}}, error) {
+{{- $args := ""}}{{range .Params}}{{$args = print $args ", " .Name}}{{end}}
+{{- if eq .Kind "exec"}}
+	return q.QueryWithContext(ctx, {{const .Name}}{{$args}}).Exec()
+{{- else}}
+	var v {{if eq .Kind "all"}}[]{{end}}{{.Type}}
+	err := q.QueryWithContext(ctx, {{const .Name}}{{$args}}).{{if eq .Kind "all"}}All{{else}}One{{end}}(&v)
+	return v, err
+{{- end}}
+}
+{{end}}`))
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/mibk/dali/dalitest"
+)
+
+func TestGenerate(t *testing.T) {
+	b, err := os.ReadFile("testdata/queries.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	queries, err := parseQueries("queries.sql", string(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := loadPackage("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg == nil || pkg.Name() != "store" {
+		t.Fatalf("got package %v, want store", pkg)
+	}
+	r := newResolver(pkg)
+	if err := r.checkTypes(queries); err != nil {
+		t.Fatal(err)
+	}
+	src, err := generate(pkg.Name(), queries, r.imports)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dalitest.Golden(t, "queries", string(src))
+}
+
+func TestParseTrailingComments(t *testing.T) {
+	queries, err := parseQueries("a.sql", "-- name: A :exec\nSELECT 1;\n\n-- Deletes what's [left]?\n"+
+		"-- name: B :exec\n-- params: id int\nDELETE FROM [t] WHERE [id] = ?\n-- The end.\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queries) != 2 || queries[0].SQL != "SELECT 1" || queries[1].SQL != "DELETE FROM [t] WHERE [id] = ?" {
+		t.Errorf("unexpected queries %+v", queries)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		err string
+	}{
+		{"SELECT 1", "a.sql:1: query without a name header"},
+		{"-- name: A\nSELECT 1", "a.sql:1: malformed header, want -- name: Name :kind [Type]"},
+		{"-- name: A-B :exec\nSELECT 1", "a.sql:1: invalid query name A-B"},
+		{"-- name: A :many User\nSELECT 1", "a.sql:1: unknown query kind :many (want :one, :all, or :exec)"},
+		{"-- name: A :one\nSELECT 1", "a.sql:1: missing result type of :one query A"},
+		{"-- name: A :exec User\nSELECT 1", "a.sql:1: unexpected result type of :exec query A"},
+		{"-- name: A :exec\n-- params: id\nSELECT ?", "a.sql:2: unnamed param of type id"},
+		{"-- name: A :exec\n-- params: ctx int\nSELECT ?", "a.sql:2: reserved param name ctx"},
+		{"-- name: A :exec\n-- params: a int,,\nSELECT ?", "a.sql:2: invalid params: a int,,"},
+		{"-- name: A :exec\n-- params: a, b int\nSELECT ?", "a.sql:1: query A has 1 placeholders, but 2 params"},
+		{"-- name: A :exec\nSELECT ?foo", "a.sql:1: query A: ?foo at offset 7: unknown placeholder ?foo"},
+		{"-- name: A :exec\n;", "a.sql:1: empty query A"},
+	}
+	for _, tt := range tests {
+		_, err := parseQueries("a.sql", tt.src)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("%q: got %v, want %s", tt.src, err, tt.err)
+		}
+	}
+}
+
+func TestCheckTypes(t *testing.T) {
+	pkg, err := loadPackage("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		typ   string
+		param string
+		err   string
+	}{
+		{"User", "", ""},
+		{"sql.NullString", "", ""},
+		{"time.Time", "d time.Duration", ""},
+		{"Group", "", "a.sql:1: undeclared result type Group"},
+		{"Count", "", "a.sql:1: result type Count is not a struct"},
+		{"*User", "", "a.sql:1: result type *User is a pointer"},
+		{"[]User", "", "a.sql:1: result type []User is not a struct"},
+		{"time.Duration", "", "a.sql:1: result type time.Duration is not a struct"},
+		{"time.User", "", "a.sql:1: undeclared type time.User"},
+		{"models.User", "", "a.sql:1: unknown package models (import it in another file of the package)"},
+		{"", "m map[string]models.Role", "a.sql:1: param m: unknown package models (import it in another file of the package)"},
+		{"", "t template.HTML", "a.sql:1: param t: unknown package template (import it in another file of the package)"},
+	}
+	for _, tt := range tests {
+		q := &query{pos: "a.sql:1", Type: tt.typ}
+		if tt.param != "" {
+			var err error
+			if q.Params, err = parseParams(tt.param); err != nil {
+				t.Fatal(err)
+			}
+		}
+		err := newResolver(pkg).checkTypes([]*query{q})
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || err.Error() != tt.err) {
+			t.Errorf("%s %s: got %v, want %q", tt.typ, tt.param, err, tt.err)
+		}
+	}
+}
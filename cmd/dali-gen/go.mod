@@ -0,0 +1,13 @@
+module github.com/mibk/dali/cmd/dali-gen
+
+go 1.26.0
+
+require (
+	github.com/mibk/dali v0.0.0-20261018224603-987906d20c07
+	golang.org/x/tools v0.51.0
+)
+
+require (
+	golang.org/x/mod v0.41.0 // indirect
+	golang.org/x/sync v0.23.0 // indirect
+)
@@ -0,0 +1,97 @@
+// Command dali-gen generates typed Go functions executing dali queries
+// from annotated SQL files. Each query is preceded by a header with
+// its name, kind, and the result type, and optionally by its params,
+// one for each placeholder:
+//
+//	-- name: FindUsersByGroup :all User
+//	-- params: groupID int64
+//	-- FindUsersByGroup returns the users in a group.
+//	SELECT * FROM [user] WHERE [group_id] = ?;
+//
+// The kind is one of :one (executed using One), :all (All), or :exec
+// (Exec). The remaining leading comments become the doc comment.
+// The query above results in
+//
+//	// FindUsersByGroup returns the users in a group.
+//	func FindUsersByGroup(ctx context.Context, q dali.Querier, groupID int64) ([]User, error)
+//
+// Result types must be structs (not pointers to them). Unqualified
+// types must be declared in the package the output file belongs to.
+// Qualified types, such as models.User or json.RawMessage, must be
+// declared in a package imported by another file of the package,
+// or in a standard library package with a unique name. Usage:
+//
+//	//go:generate dali-gen -o queries_gen.go queries/*.sql
+//
+// The files can be loaded by dali.LoadQueries as well.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var (
+	output  = flag.String("o", "dali_gen.go", "output `file`")
+	pkgName = flag.String("pkg", "", "package `name` (default: the package in the output directory)")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: dali-gen [flags] file.sql...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(*output, *pkgName, flag.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "dali-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(output, pkg string, files []string) error {
+	var queries []*query
+	seen := make(map[string]string)
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		qs, err := parseQueries(file, string(b))
+		if err != nil {
+			return err
+		}
+		for _, q := range qs {
+			if prev, ok := seen[q.Name]; ok {
+				return fmt.Errorf("%s: query %s already defined at %s", q.pos, q.Name, prev)
+			}
+			seen[q.Name] = q.pos
+		}
+		queries = append(queries, qs...)
+	}
+
+	p, err := loadPackage(filepath.Dir(output))
+	if err != nil {
+		return err
+	}
+	if pkg == "" && p != nil {
+		pkg = p.Name()
+	}
+	if pkg == "" {
+		return fmt.Errorf("cannot determine the package name, use -pkg")
+	}
+	r := newResolver(p)
+	if err := r.checkTypes(queries); err != nil {
+		return err
+	}
+	src, err := generate(pkg, queries, r.imports)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(output, src, 0o644)
+}
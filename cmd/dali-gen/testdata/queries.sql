@@ -0,0 +1,25 @@
+-- Queries working with users.
+
+-- name: FindUser :one User
+-- params: id int64
+SELECT * FROM [user] WHERE [id] = ?;
+
+-- name: FindUsersByGroup :all User
+-- params: groupID int64, since time.Time
+-- FindUsersByGroup returns the users in a group
+-- created after since.
+SELECT * FROM [user]
+WHERE [group_id] = ? AND [created] > ?
+ORDER BY `name`;
+
+-- Inserts a user; the ID is ignored?
+-- name: InsertUser :exec
+-- params: u User
+INSERT INTO [user] ?values;
+
+-- name: DeleteAll :exec
+DELETE FROM [user]
+
+-- name: SetSettings :exec
+-- params: settings json.RawMessage, id int64
+UPDATE [user] SET [settings] = ? WHERE [id] = ?
@@ -0,0 +1,12 @@
+package store
+
+import "time"
+
+type User struct {
+	ID      int64
+	Name    string
+	GroupID int64
+	Created time.Time
+}
+
+type Count int
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/types"
+	pathpkg "path"
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+const daliPath = "github.com/mibk/dali"
+
+// generated are the packages imported by every generated file,
+// or by files with :exec queries (database/sql).
+var generated = map[string]string{
+	"context": "context",
+	"sql":     "database/sql",
+	"dali":    daliPath,
+}
+
+var versionRx = regexp.MustCompile(`^v[0-9]+$`)
+
+// loadPackage type-checks the package in dir. It returns nil if
+// there is no package yet. Type errors are ignored: the package may
+// use functions of the file being generated.
+func loadPackage(dir string) (*types.Package, error) {
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedTypes | packages.NeedImports,
+		Dir:  dir,
+	}
+	pkgs, err := packages.Load(cfg, ".")
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 || len(pkgs[0].GoFiles) == 0 {
+		return nil, nil
+	}
+	if err := loadError(pkgs[0]); err != nil {
+		return nil, err
+	}
+	return pkgs[0].Types, nil
+}
+
+// loadError returns the first error of p other than a type error.
+func loadError(p *packages.Package) error {
+	for _, err := range p.Errors {
+		if err.Kind != packages.TypeError {
+			return err
+		}
+	}
+	return nil
+}
+
+// A resolver resolves the types used by the queries. Qualified types
+// must be declared in packages imported by the package the output
+// file belongs to, or in standard library packages with unique names.
+type resolver struct {
+	pkg *types.Package // nil if there is no package yet
+
+	std     map[string][]string // std package name to paths, loaded lazily
+	imports map[string]string   // used package name to path
+}
+
+func newResolver(pkg *types.Package) *resolver {
+	return &resolver{pkg: pkg, imports: make(map[string]string)}
+}
+
+// checkTypes resolves the types of the params and the results
+// of the queries and checks that the result types are structs.
+func (r *resolver) checkTypes(queries []*query) error {
+	for _, q := range queries {
+		for _, p := range q.Params {
+			if err := r.checkParam(p.Type); err != nil {
+				return fmt.Errorf("%s: param %s: %v", q.pos, p.Name, err)
+			}
+		}
+		if q.Type == "" {
+			continue
+		}
+		if err := r.checkResult(q.Type); err != nil {
+			return fmt.Errorf("%s: %v", q.pos, err)
+		}
+	}
+	return nil
+}
+
+// checkParam resolves the qualified types used by typ.
+func (r *resolver) checkParam(typ string) error {
+	expr, err := parser.ParseExpr(typ)
+	if err != nil {
+		return err
+	}
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok && err == nil {
+			_, err = r.lookup(sel)
+			return false
+		}
+		return err == nil
+	})
+	return err
+}
+
+func (r *resolver) checkResult(typ string) error {
+	expr, err := parser.ParseExpr(typ)
+	if err != nil {
+		return err
+	}
+	var obj types.Object
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return fmt.Errorf("result type %s is a pointer", typ)
+	case *ast.Ident:
+		if r.pkg != nil {
+			obj = r.pkg.Scope().Lookup(e.Name)
+		}
+		if obj == nil {
+			return fmt.Errorf("undeclared result type %s", typ)
+		}
+	case *ast.SelectorExpr:
+		if obj, err = r.lookup(e); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("result type %s is not a struct", typ)
+	}
+	if _, ok := obj.(*types.TypeName); !ok {
+		return fmt.Errorf("%s is not a type", typ)
+	}
+	if _, ok := obj.Type().Underlying().(*types.Struct); !ok {
+		return fmt.Errorf("result type %s is not a struct", typ)
+	}
+	return nil
+}
+
+// lookup returns the type named by sel, a qualified identifier,
+// and records the package of the type as used.
+func (r *resolver) lookup(sel *ast.SelectorExpr) (types.Object, error) {
+	id, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return nil, fmt.Errorf("invalid type %s", types.ExprString(sel))
+	}
+	pkg, err := r.lookupPackage(id.Name)
+	if err != nil {
+		return nil, err
+	}
+	if path, ok := generated[id.Name]; ok && path != pkg.Path() {
+		return nil, fmt.Errorf("package %s (%s) conflicts with %s", id.Name, pkg.Path(), path)
+	}
+	obj := pkg.Scope().Lookup(sel.Sel.Name)
+	if _, ok := obj.(*types.TypeName); !ok || !obj.Exported() {
+		return nil, fmt.Errorf("undeclared type %s", types.ExprString(sel))
+	}
+	r.imports[id.Name] = pkg.Path()
+	return obj, nil
+}
+
+// lookupPackage returns the package named name.
+func (r *resolver) lookupPackage(name string) (*types.Package, error) {
+	var found []*types.Package
+	if r.pkg != nil {
+		for _, p := range r.pkg.Imports() {
+			if p.Name() == name {
+				found = append(found, p)
+			}
+		}
+	}
+	if len(found) > 1 {
+		return nil, fmt.Errorf("ambiguous package %s: %s, %s", name, found[0].Path(), found[1].Path())
+	}
+	if len(found) == 1 {
+		return found[0], nil
+	}
+	if err := r.loadStd(); err != nil {
+		return nil, err
+	}
+	if paths := r.std[name]; len(paths) == 1 {
+		pkgs, err := packages.Load(&packages.Config{Mode: packages.NeedName | packages.NeedTypes}, paths[0])
+		if err != nil {
+			return nil, err
+		}
+		if len(pkgs) == 1 {
+			if err := loadError(pkgs[0]); err != nil {
+				return nil, err
+			}
+			return pkgs[0].Types, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown package %s (import it in another file of the package)", name)
+}
+
+// loadStd loads the names of the standard library packages.
+// Internal packages and packages with a major version suffix,
+// such as math/rand/v2, are omitted.
+func (r *resolver) loadStd() error {
+	if r.std != nil {
+		return nil
+	}
+	pkgs, err := packages.Load(&packages.Config{Mode: packages.NeedName}, "std")
+	if err != nil {
+		return err
+	}
+	r.std = make(map[string][]string)
+	for _, p := range pkgs {
+		if strings.Contains(p.PkgPath, "internal") || strings.HasPrefix(p.PkgPath, "vendor/") ||
+			versionRx.MatchString(pathpkg.Base(p.PkgPath)) {
+			continue
+		}
+		r.std[p.Name] = append(r.std[p.Name], p.PkgPath)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/mibk/dali"
+)
+
+// table is a table with its columns.
+type table struct {
+	Name    string
+	Columns []dali.Column
+}
+
+// loadTables loads the named tables, or all tables if names is empty.
+func loadTables(ctx context.Context, db *dali.DB, names []string) ([]table, error) {
+	if len(names) == 0 {
+		all, err := db.Tables(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range all {
+			names = append(names, t.Name)
+		}
+		if len(names) == 0 {
+			return nil, nil
+		}
+	}
+	cols, err := db.Columns(ctx, names...)
+	if err != nil {
+		return nil, err
+	}
+	byTable := make(map[string][]dali.Column)
+	for _, c := range cols {
+		byTable[c.Table] = append(byTable[c.Table], c)
+	}
+	var tables []table
+	for _, name := range names {
+		cols, ok := byTable[name]
+		if !ok {
+			return nil, fmt.Errorf("table %s not found", name)
+		}
+		tables = append(tables, table{name, cols})
+	}
+	return tables, nil
+}
+
+// generate returns the source of the package pkg with a struct
+// for each of the tables.
+func generate(pkg string, tables []table) ([]byte, error) {
+	imports := make(map[string]bool)
+	var body bytes.Buffer
+	for _, t := range tables {
+		name := goName(t.Name)
+		fmt.Fprintf(&body, "\n// %s represents a row of the %s table.\n", name, t.Name)
+		fmt.Fprintf(&body, "type %s struct {\n", name)
+		for _, c := range t.Columns {
+			typ := goType(c)
+			if i := strings.IndexByte(typ, '.'); i != -1 {
+				imports[importPaths[typ[:i]]] = true
+			}
+			tag := c.Name
+			if c.AutoIncrement {
+				tag += ",selectonly"
+			}
+			if c.PrimaryKey {
+				tag += ",pk"
+			}
+			fmt.Fprintf(&body, "\t%s %s `db:%q`\n", goName(c.Name), typ, tag)
+		}
+		body.WriteString("}\n")
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by dali-genstruct. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n", pkg)
+	switch len(imports) {
+	case 0:
+	case 1:
+		for path := range imports {
+			fmt.Fprintf(&buf, "\nimport %q\n", path)
+		}
+	default:
+		var paths []string
+		for path := range imports {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		buf.WriteString("\nimport (\n")
+		for _, path := range paths {
+			fmt.Fprintf(&buf, "\t%q\n", path)
+		}
+		buf.WriteString(")\n")
+	}
+	buf.Write(body.Bytes())
+	return format.Source(buf.Bytes())
+}
+
+var importPaths = map[string]string{
+	"sql":  "database/sql",
+	"time": "time",
+}
+
+// goType returns the Go type of values of c.
+func goType(c dali.Column) string {
+	unsigned := strings.Contains(c.ColumnType, "unsigned")
+	var typ, null string
+	switch c.DataType {
+	case "tinyint":
+		if strings.HasPrefix(c.ColumnType, "tinyint(1)") {
+			typ, null = "bool", "sql.NullBool"
+			break
+		}
+		fallthrough
+	case "smallint", "mediumint", "int", "integer", "year":
+		typ, null = "int64", "sql.NullInt64"
+	case "bigint":
+		typ, null = "int64", "sql.NullInt64"
+		if unsigned {
+			typ, null = "uint64", "*uint64"
+		}
+	case "float", "double", "real":
+		typ, null = "float64", "sql.NullFloat64"
+	case "date", "datetime", "timestamp":
+		typ, null = "time.Time", "sql.NullTime"
+	case "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob", "bit":
+		typ, null = "[]byte", "[]byte"
+	default:
+		// char, varchar, text, enum, set, decimal, time, json, etc.
+		typ, null = "string", "sql.NullString"
+	}
+	if c.Nullable {
+		return null
+	}
+	return typ
+}
+
+// commonInitialisms are written in upper case in Go names.
+var commonInitialisms = map[string]bool{
+	"API": true, "CSS": true, "DNS": true, "HTML": true, "HTTP": true,
+	"ID": true, "IP": true, "JSON": true, "SQL": true, "URI": true,
+	"URL": true, "UTC": true, "UUID": true, "XML": true,
+}
+
+// goName converts an SQL name, such as user_id, to a Go name (UserID).
+func goName(s string) string {
+	var b strings.Builder
+	for _, w := range strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if u := strings.ToUpper(w); commonInitialisms[u] {
+			b.WriteString(u)
+			continue
+		}
+		r := []rune(w)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+	name := b.String()
+	if name == "" || !unicode.IsLetter([]rune(name)[0]) {
+		name = "X" + name
+	}
+	return name
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mibk/dali/dalitest"
+	"github.com/mibk/dali/dialect"
+)
+
+var columns = []string{"table", "name", "data_type", "column_type", "nullable", "primary_key", "auto_increment"}
+
+func TestGenerate(t *testing.T) {
+	db, mock := dalitest.New(dialect.MySQL)
+	mock.ExpectQueryRegexp("^SELECT `TABLE_NAME` AS `name`, .* FROM `information_schema`.`TABLES` ").WillReturnRows(
+		dalitest.NewRows("name", "view").AddRow("user", 0).AddRow("user_group", 0))
+	userGroup := [][]interface{}{
+		{"user_group", "user_id", "bigint", "bigint(20)", 0, 1, 0},
+		{"user_group", "group_id", "int", "int(11)", 0, 1, 0},
+		{"user_group", "weight", "double", "double", 1, 0, 0},
+		{"user_group", "api_url", "text", "text", 0, 0, 0},
+	}
+	rows := dalitest.NewRows(columns...).
+		AddRow("user", "id", "bigint", "bigint(20) unsigned", 0, 1, 1).
+		AddRow("user", "group_id", "int", "int(11)", 1, 0, 0).
+		AddRow("user", "email", "varchar", "varchar(255)", 0, 0, 0).
+		AddRow("user", "active", "tinyint", "tinyint(1)", 0, 0, 0).
+		AddRow("user", "avatar", "blob", "blob", 1, 0, 0).
+		AddRow("user", "created", "datetime", "datetime", 0, 0, 0).
+		AddRow("user", "deleted", "datetime", "datetime", 1, 0, 0)
+	for _, r := range userGroup {
+		rows.AddRow(r...)
+	}
+	// The columns of all tables are loaded by a single query.
+	mock.ExpectQueryRegexp("^SELECT `TABLE_NAME` AS `table`.* `TABLE_NAME` IN \\('user', 'user_group'\\) ").
+		WillReturnRows(rows)
+
+	tables, err := loadTables(context.Background(), db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+	src, err := generate("model", tables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dalitest.Golden(t, "model", string(src))
+
+	rows = dalitest.NewRows(columns...)
+	for _, r := range userGroup {
+		rows.AddRow(r...)
+	}
+	mock.ExpectQueryRegexp("`TABLE_NAME` IN \\('user_group'\\) ").WillReturnRows(rows)
+	if tables, err = loadTables(context.Background(), db, []string{"user_group"}); err != nil {
+		t.Fatal(err)
+	}
+	if src, err = generate("model", tables); err != nil {
+		t.Fatal(err)
+	}
+	dalitest.Golden(t, "user_group", string(src))
+
+	mock.ExpectQueryRegexp("`TABLE_NAME` IN \\('missing'\\) ").WillReturnRows(dalitest.NewRows(columns...))
+	if _, err := loadTables(context.Background(), db, []string{"missing"}); err == nil || err.Error() != "table missing not found" {
+		t.Errorf("got %v, want table not found", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGoName(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"user", "User"},
+		{"user_id", "UserID"},
+		{"api_url", "APIURL"},
+		{"ordinal-position", "OrdinalPosition"},
+		{"2fa", "X2fa"},
+		{"Created", "Created"},
+	}
+	for _, tt := range tests {
+		if got := goName(tt.in); got != tt.want {
+			t.Errorf("goName(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
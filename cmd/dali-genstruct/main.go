@@ -0,0 +1,83 @@
+// Command dali-genstruct generates Go structs from the schema of a live
+// database, to be loaded by dali. Currently only MySQL is supported.
+//
+//	dali-genstruct -dsn 'user:pass@/shop' -pkg model -o model/tables.go
+//
+// Each table results in a struct named after it, e.g. user_group becomes
+// UserGroup. The fields are tagged with the column names; auto-incremented
+// columns are marked selectonly and primary key columns pk:
+//
+//	type UserGroup struct {
+//		ID   int64          `db:"id,selectonly,pk"`
+//		Name sql.NullString `db:"name"`
+//	}
+//
+// Loading time.Time fields from MySQL requires the parseTime=true
+// parameter in the DSN.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	_ "github.com/go-sql-driver/mysql"
+
+	"github.com/mibk/dali"
+	"github.com/mibk/dali/dialect"
+)
+
+var (
+	dsn     = flag.String("dsn", os.Getenv("DALI_DSN"), "MySQL data source `name` (default $DALI_DSN)")
+	output  = flag.String("o", "", "output `file` (default stdout)")
+	pkgName = flag.String("pkg", "model", "package `name`")
+	tables  = flag.String("tables", "", "comma-separated `list` of tables (default all)")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: dali-genstruct [flags]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() > 0 || *dsn == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "dali-genstruct: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	sqldb, err := sql.Open("mysql", *dsn)
+	if err != nil {
+		return err
+	}
+	defer sqldb.Close()
+	db := dali.NewDB(sqldb, dialect.MySQL)
+
+	var names []string
+	if *tables != "" {
+		for _, name := range strings.Split(*tables, ",") {
+			names = append(names, strings.TrimSpace(name))
+		}
+	}
+	tables, err := loadTables(context.Background(), db, names)
+	if err != nil {
+		return err
+	}
+	src, err := generate(*pkgName, tables)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err := os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(*output, src, 0o644)
+}
@@ -0,0 +1,109 @@
+package dali
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/mibk/dali/dialect"
+)
+
+// Conn is a single database connection reserved from the pool of a DB.
+// Unlike the DB, it guarantees that all queries run in the same session,
+// which is needed for session state such as variables, temporary tables,
+// or locks held by the connection.
+type Conn struct {
+	Conn       *sql.Conn
+	dialect    dialect.Dialect
+	middleware Middleware
+	hooks      []Hook
+}
+
+var _ Querier = (*Conn)(nil)
+
+// Conn returns a single connection by either opening a new connection
+// or returning an existing connection from the connection pool. Conn
+// will block until either a connection is returned or ctx is canceled.
+// Queries run on the same Conn will be run in the same database session.
+//
+// Every Conn must be returned to the pool after use by calling Close.
+func (db *DB) Conn(ctx context.Context) (*Conn, error) {
+	conn, err := db.DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{
+		Conn:       conn,
+		dialect:    db.dialect,
+		middleware: db.middleware(),
+		hooks:      append([]Hook(nil), db.hooks...),
+	}, nil
+}
+
+// QueryWithContext is a (*DB).QueryWithContext equivalent for
+// the connection.
+func (c *Conn) QueryWithContext(ctx context.Context, query string, args ...interface{}) *Query {
+	sql, err := translate(c.dialect, query, args)
+	return &Query{
+		ctx:    ctx,
+		execer: c.middleware(c.Conn),
+		query:  sql,
+		err:    err,
+		event: QueryEvent{
+			Template: query,
+			Args:     args,
+			SQL:      sql,
+			Conn:     c,
+			Dialect:  c.dialect,
+		},
+	}
+}
+
+// Query is a (*DB).Query equivalent for the connection.
+func (c *Conn) Query(query string, args ...interface{}) *Query {
+	return c.QueryWithContext(context.Background(), query, args...)
+}
+
+// PrepareContext is a (*DB).PrepareContext equivalent for
+// the connection.
+func (c *Conn) PrepareContext(ctx context.Context, query string, args ...interface{}) (*Stmt, error) {
+	sql, err := translatePreparedStmt(c.dialect, query, args)
+	if err != nil {
+		return nil, err
+	}
+	ev := QueryEvent{
+		Template: query,
+		Args:     args,
+		SQL:      sql,
+		Prepared: true,
+		Conn:     c,
+		Dialect:  c.dialect,
+	}
+	done := callHooks(ctx, c.hooks, OpPrepare, ev)
+	stmt, err := c.Conn.PrepareContext(ctx, sql)
+	done(err)
+	if err != nil {
+		return nil, err
+	}
+	return &Stmt{
+		stmt:       stmt,
+		sql:        sql,
+		middleware: c.middleware,
+		event:      ev,
+	}, nil
+}
+
+// Prepare is a (*DB).Prepare equivalent for the connection.
+func (c *Conn) Prepare(query string, args ...interface{}) (*Stmt, error) {
+	return c.PrepareContext(context.Background(), query, args...)
+}
+
+// BeginTx starts a transaction on the connection.
+// See (*DB).BeginTx for details.
+func (c *Conn) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	return beginTx(ctx, opts, c.dialect, c.middleware, c.hooks, c.Conn.BeginTx)
+}
+
+// Close returns the connection to the connection pool.
+func (c *Conn) Close() error {
+	return c.Conn.Close()
+}
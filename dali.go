@@ -12,21 +12,27 @@ import (
 // is Query which provides methods for executing queries
 // or scanning results.
 type DB struct {
-	DB         *sql.DB
-	dialect    dialect.Dialect
-	middleware func(Execer) Execer
+	DB          *sql.DB
+	execer      Execer // if nil, DB is used
+	dialect     dialect.Dialect
+	middlewares []Middleware
+	hooks       []Hook
 }
 
 // NewDB instantiates DB from the given database/sql DB handle
 // in the particular dialect.
 func NewDB(db *sql.DB, d dialect.Dialect) *DB {
 	return &DB{
-		DB:         db,
-		dialect:    d,
-		middleware: func(e Execer) Execer { return e },
+		DB:      db,
+		dialect: d,
 	}
 }
 
+// Dialect returns the dialect of db.
+func (db *DB) Dialect() dialect.Dialect {
+	return db.dialect
+}
+
 // Open opens a database by calling sql.Open. It returns a new DB and
 // selects the appropriate dialect which is inferred from the driverName.
 // It panics if the dialect is not supported by dali itself.
@@ -59,13 +65,25 @@ func (db *DB) Ping() error {
 // QueryWithContext is a fundamental method of DB. It returns a Query struct
 // which is capable of executing the sql (given by the query and
 // the args) or loading the result into structs or primitive values.
+//
+// If ctx carries a transaction (see WithTx), the query is run
+// within that transaction.
 func (db *DB) QueryWithContext(ctx context.Context, query string, args ...interface{}) *Query {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.QueryWithContext(ctx, query, args...)
+	}
 	sql, err := translate(db.dialect, query, args)
 	return &Query{
 		ctx:    ctx,
-		execer: db.middleware(db.DB),
+		execer: db.middleware()(db.baseExecer()),
 		query:  sql,
 		err:    err,
+		event: QueryEvent{
+			Template: query,
+			Args:     args,
+			SQL:      sql,
+			Dialect:  db.dialect,
+		},
 	}
 }
 
@@ -86,17 +104,35 @@ func (db *DB) Query(query string, args ...interface{}) *Query {
 // binding.
 //
 // The provided context is used for the preparation of the statement, not
-// for the execution of the statement.
+// for the execution of the statement. If ctx carries a transaction (see
+// WithTx), the statement is prepared within that transaction.
 func (db *DB) PrepareContext(ctx context.Context, query string, args ...interface{}) (*Stmt, error) {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.PrepareContext(ctx, query, args...)
+	}
 	sql, err := translatePreparedStmt(db.dialect, query, args)
 	if err != nil {
 		return nil, err
 	}
+	ev := QueryEvent{
+		Template: query,
+		Args:     args,
+		SQL:      sql,
+		Prepared: true,
+		Dialect:  db.dialect,
+	}
+	done := callHooks(ctx, db.hooks, OpPrepare, ev)
 	stmt, err := db.DB.PrepareContext(ctx, sql)
+	done(err)
 	if err != nil {
 		return nil, err
 	}
-	return &Stmt{stmt, sql, db.middleware}, nil
+	return &Stmt{
+		stmt:       stmt,
+		sql:        sql,
+		middleware: db.middleware(),
+		event:      ev,
+	}, nil
 }
 
 // Prepare creates a prepared statement for later queries or executions.
@@ -122,15 +158,7 @@ func (db *DB) Prepare(query string, args ...interface{}) (*Stmt, error) {
 // If a non-default isolation level is used that the driver doesn't support,
 // an error will be returned.
 func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
-	tx, err := db.DB.BeginTx(ctx, opts)
-	if err != nil {
-		return nil, err
-	}
-	return &Tx{
-		Tx:         tx,
-		dialect:    db.dialect,
-		middleware: db.middleware,
-	}, nil
+	return beginTx(ctx, opts, db.dialect, db.middleware(), db.hooks, db.DB.BeginTx)
 }
 
 // Begin starts a transaction. The isolation level is dependent on
@@ -139,14 +167,79 @@ func (db *DB) Begin() (*Tx, error) {
 	return db.BeginTx(context.Background(), nil)
 }
 
-// SetMiddlewareFunc changes the DB middleware func. Default func
-// passes the Execer unchanged. SetMiddlewareFunc allowes the user
-// to set his own middleware to perform additional operations (e.g.
-// profiling) when executing queries.
+// Use appends mws to the DB middleware chain. Middlewares allow the user
+// to perform additional operations (e.g. profiling) when executing
+// queries. They are applied in the order in which they were added,
+// the first one being the outermost, i.e. the first one to see a query.
+// Middlewares can learn more about the executed query from the QueryEvent
+// carried by the context (see QueryEventFromContext).
+//
+// Transactions and prepared statements use the chain that was in effect
+// when they were created.
+func (db *DB) Use(mws ...Middleware) {
+	db.middlewares = append(db.middlewares, mws...)
+}
+
+// SetMiddlewareFunc replaces the whole DB middleware chain with f.
+//
+// Deprecated: Use the Use method, which doesn't discard middlewares
+// added before.
 func (db *DB) SetMiddlewareFunc(f func(Execer) Execer) {
-	db.middleware = f
+	db.middlewares = []Middleware{f}
 }
 
+// UseHooks appends hooks to the DB hooks. Unlike middlewares, hooks
+// are notified about operations that are not executed through an Execer:
+// beginning, committing, and rolling back transactions and preparing
+// statements.
+//
+// Transactions use the hooks that were in effect when they were begun.
+func (db *DB) UseHooks(hooks ...Hook) {
+	db.hooks = append(db.hooks, hooks...)
+}
+
+func (db *DB) baseExecer() Execer {
+	if db.execer != nil {
+		return db.execer
+	}
+	return db.DB
+}
+
+func (db *DB) middleware() Middleware {
+	return chain(db.middlewares)
+}
+
+// Middleware wraps an Execer to perform additional operations
+// when executing queries.
+type Middleware func(Execer) Execer
+
+// chain composes mws into a single Middleware. The first
+// middleware is the outermost one.
+func chain(mws []Middleware) Middleware {
+	mws = append([]Middleware(nil), mws...)
+	return func(e Execer) Execer {
+		for i := len(mws) - 1; i >= 0; i-- {
+			e = mws[i](e)
+		}
+		return e
+	}
+}
+
+// Querier is the interface implemented by both DB and Tx. It allows
+// writing functions that work the same way whether or not they run
+// inside a transaction.
+type Querier interface {
+	Query(query string, args ...interface{}) *Query
+	QueryWithContext(ctx context.Context, query string, args ...interface{}) *Query
+	Prepare(query string, args ...interface{}) (*Stmt, error)
+	PrepareContext(ctx context.Context, query string, args ...interface{}) (*Stmt, error)
+}
+
+var (
+	_ Querier = (*DB)(nil)
+	_ Querier = (*Tx)(nil)
+)
+
 // Execer is an interface that Query works with.
 type Execer interface {
 	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
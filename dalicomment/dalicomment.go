@@ -0,0 +1,136 @@
+// Package dalicomment provides a dali middleware appending request
+// metadata to executed queries as SQL comments, following the
+// sqlcommenter format:
+//
+//	SELECT * FROM `user` /*request_id='4bf92f35',route='%2Fusers'*/
+//
+// This makes it possible to attribute queries seen in the process list
+// or slow log of the database to services and endpoints.
+//
+//	db.Use(dalicomment.Middleware(nil))
+//	...
+//	ctx = dalicomment.WithTag(ctx, "route", "/users")
+//	db.QueryWithContext(ctx, ...)
+package dalicomment
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/mibk/dali"
+)
+
+type tagsKey struct{}
+
+// WithTag returns a copy of ctx carrying the tag key with value,
+// which is going to be added to the comments of queries executed
+// with the returned context.
+func WithTag(ctx context.Context, key, value string) context.Context {
+	old, _ := ctx.Value(tagsKey{}).(map[string]string)
+	tags := make(map[string]string, len(old)+1)
+	for k, v := range old {
+		tags[k] = v
+	}
+	tags[key] = value
+	return context.WithValue(ctx, tagsKey{}, tags)
+}
+
+// Options configure the commenting middleware. A nil *Options is
+// equivalent to the zero value.
+type Options struct {
+	// Tags, if set, returns additional tags for a query executed with
+	// ctx, such as a trace ID. Tags set by WithTag take precedence.
+	Tags func(ctx context.Context) map[string]string
+}
+
+// Middleware returns a dali.Middleware appending the tags carried by
+// the query context as a comment to the query. Prepared statements
+// are left intact as their SQL cannot change after the preparation.
+func Middleware(opts *Options) dali.Middleware {
+	if opts == nil {
+		opts = new(Options)
+	}
+	return func(e dali.Execer) dali.Execer {
+		return &execer{ex: e, tags: opts.Tags}
+	}
+}
+
+type execer struct {
+	ex   dali.Execer
+	tags func(ctx context.Context) map[string]string
+}
+
+func (e *execer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return e.ex.ExecContext(ctx, e.comment(ctx, query), args...)
+}
+
+func (e *execer) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return e.ex.QueryContext(ctx, e.comment(ctx, query), args...)
+}
+
+func (e *execer) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return e.ex.QueryRowContext(ctx, e.comment(ctx, query), args...)
+}
+
+func (e *execer) comment(ctx context.Context, query string) string {
+	if ev, ok := dali.QueryEventFromContext(ctx); ok && ev.Prepared {
+		return query
+	}
+	tags, _ := ctx.Value(tagsKey{}).(map[string]string)
+	if e.tags != nil {
+		merged := make(map[string]string)
+		for k, v := range e.tags(ctx) {
+			merged[k] = v
+		}
+		for k, v := range tags {
+			merged[k] = v
+		}
+		tags = merged
+	}
+	return Append(query, tags)
+}
+
+// Append appends tags to query as a comment. The keys and values
+// are URL-encoded, so the comment can contain neither quotes nor
+// the comment terminator, and it is never interpreted as an optimizer
+// hint or as a MySQL executable comment. If query ends with a semicolon,
+// the comment is inserted before it.
+func Append(query string, tags map[string]string) string {
+	if len(tags) == 0 {
+		return query
+	}
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	trimmed := strings.TrimRight(query, " \t\r\n")
+	semicolon := strings.HasSuffix(trimmed, ";")
+	trimmed = strings.TrimSuffix(trimmed, ";")
+
+	b := new(strings.Builder)
+	b.WriteString(trimmed)
+	b.WriteString(" /*")
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(escape(k))
+		b.WriteString("='")
+		b.WriteString(escape(tags[k]))
+		b.WriteByte('\'')
+	}
+	b.WriteString("*/")
+	if semicolon {
+		b.WriteByte(';')
+	}
+	return b.String()
+}
+
+func escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
@@ -0,0 +1,59 @@
+package dalicomment
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/mibk/dali"
+	"github.com/mibk/dali/dialect"
+	"github.com/mibk/dali/internal/testdriver"
+)
+
+func TestAppend(t *testing.T) {
+	tests := []struct {
+		query string
+		tags  map[string]string
+		want  string
+	}{
+		{"SELECT 1", nil, "SELECT 1"},
+		{"SELECT 1", map[string]string{"route": "/users/{id}", "action": "show"},
+			"SELECT 1 /*action='show',route='%2Fusers%2F%7Bid%7D'*/"},
+		{"SELECT 1;\n", map[string]string{"app": "admin"}, "SELECT 1 /*app='admin'*/;"},
+		{"SELECT 1", map[string]string{"x": "it's */ DROP TABLE"},
+			"SELECT 1 /*x='it%27s%20%2A%2F%20DROP%20TABLE'*/"},
+		{"SELECT 1", map[string]string{"!50000 k": "v"}, "SELECT 1 /*%2150000%20k='v'*/"},
+	}
+	for _, tt := range tests {
+		if got := Append(tt.query, tt.tags); got != tt.want {
+			t.Errorf("\n got: %v\nwant: %v", got, tt.want)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	drv, handle := testdriver.New()
+	db := dali.NewDB(handle, dialect.MySQL)
+	db.Use(Middleware(&Options{
+		Tags: func(ctx context.Context) map[string]string {
+			return map[string]string{"traceid": "abc", "route": "default"}
+		},
+	}))
+
+	ctx := WithTag(context.Background(), "route", "/users")
+	ctx = WithTag(ctx, "request_id", "42")
+	db.QueryWithContext(ctx, "SELECT * FROM [user]").Rows()
+	stmt, err := db.PrepareContext(ctx, "SELECT ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt.BindContext(ctx, 1).Exec()
+
+	want := []string{
+		"SELECT * FROM `user` /*request_id='42',route='%2Fusers',traceid='abc'*/",
+		"SELECT ?",
+	}
+	if got := drv.Queries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("\n got: %q\nwant: %q", got, want)
+	}
+}
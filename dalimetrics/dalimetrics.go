@@ -0,0 +1,169 @@
+// Package dalimetrics provides a dali middleware reporting query
+// metrics, such as counts and latencies, per query template.
+//
+// The metrics are reported through a Collector. The labels of an
+// Observation are designed to map directly onto Prometheus vectors:
+//
+//	type promCollector struct {
+//		total    *prometheus.CounterVec   // with dalimetrics.LabelNames
+//		duration *prometheus.HistogramVec // with dalimetrics.LabelNames
+//	}
+//
+//	func (c promCollector) Observe(o dalimetrics.Observation) {
+//		labels := o.LabelValues()
+//		c.total.WithLabelValues(labels...).Inc()
+//		c.duration.WithLabelValues(labels...).Observe(o.Duration.Seconds())
+//	}
+package dalimetrics
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/mibk/dali"
+)
+
+// Observation is a single query execution.
+type Observation struct {
+	// Template is the query template before translation, or
+	// empty if the query was not executed by dali (e.g. it was
+	// executed by another middleware).
+	Template string
+
+	// SQL is the query passed to the Execer. It is not used as
+	// a label, as it contains the interpolated args.
+	SQL string
+
+	Op       dali.Op
+	Failed   bool
+	Duration time.Duration
+}
+
+// LabelNames are the names of the labels returned
+// by Observation.LabelValues.
+var LabelNames = []string{"template", "op", "status"}
+
+// NoTemplate is the value of the template label of queries
+// that have no template.
+const NoTemplate = "none"
+
+// LabelValues returns the values of the labels named by LabelNames.
+func (o Observation) LabelValues() []string {
+	template := o.Template
+	if template == "" {
+		template = NoTemplate
+	}
+	status := "ok"
+	if o.Failed {
+		status = "error"
+	}
+	return []string{template, o.Op.String(), status}
+}
+
+// A Collector records observations of executed queries. It must be
+// safe for concurrent use.
+type Collector interface {
+	Observe(o Observation)
+}
+
+// Middleware returns a dali.Middleware reporting every executed
+// query to c.
+func Middleware(c Collector) dali.Middleware {
+	return func(e dali.Execer) dali.Execer {
+		return &execer{ex: e, c: c}
+	}
+}
+
+type execer struct {
+	ex dali.Execer
+	c  Collector
+}
+
+func (e *execer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	res, err := e.ex.ExecContext(ctx, query, args...)
+	e.observe(ctx, query, dali.OpExec, start, err)
+	return res, err
+}
+
+func (e *execer) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := e.ex.QueryContext(ctx, query, args...)
+	e.observe(ctx, query, dali.OpRows, start, err)
+	return rows, err
+}
+
+func (e *execer) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := e.ex.QueryRowContext(ctx, query, args...)
+	e.observe(ctx, query, dali.OpScanRow, start, row.Err())
+	return row
+}
+
+func (e *execer) observe(ctx context.Context, query string, op dali.Op, start time.Time, err error) {
+	o := Observation{
+		SQL:      query,
+		Op:       op,
+		Failed:   err != nil,
+		Duration: time.Since(start),
+	}
+	if ev, ok := dali.QueryEventFromContext(ctx); ok {
+		o.Template, o.Op = ev.Template, ev.Op
+	}
+	e.c.Observe(o)
+}
+
+// Memory is a Collector keeping all observations in memory.
+// It is meant for tests.
+type Memory struct {
+	mu  sync.Mutex
+	obs []Observation
+}
+
+// Observe implements Collector.
+func (m *Memory) Observe(o Observation) {
+	m.mu.Lock()
+	m.obs = append(m.obs, o)
+	m.mu.Unlock()
+}
+
+// Observations returns all observations in the order
+// in which they were recorded.
+func (m *Memory) Observations() []Observation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Observation(nil), m.obs...)
+}
+
+// Count returns the number of executions of template with op
+// that either failed or succeeded.
+func (m *Memory) Count(template string, op dali.Op, failed bool) int {
+	n := 0
+	for _, o := range m.Observations() {
+		if o.Template == template && o.Op == op && o.Failed == failed {
+			n++
+		}
+	}
+	return n
+}
+
+// Durations returns the durations of all executions
+// of template with op.
+func (m *Memory) Durations(template string, op dali.Op) []time.Duration {
+	var d []time.Duration
+	for _, o := range m.Observations() {
+		if o.Template == template && o.Op == op {
+			d = append(d, o.Duration)
+		}
+	}
+	return d
+}
+
+// Reset discards all observations.
+func (m *Memory) Reset() {
+	m.mu.Lock()
+	m.obs = nil
+	m.mu.Unlock()
+}
@@ -0,0 +1,83 @@
+package dalimetrics
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/mibk/dali"
+	"github.com/mibk/dali/dialect"
+	"github.com/mibk/dali/internal/testdriver"
+)
+
+type user struct {
+	ID   int64 `db:"id"`
+	Name string
+}
+
+func TestMiddleware(t *testing.T) {
+	drv, handle := testdriver.New()
+	drv.SetRows([]string{"id"})
+	db := dali.NewDB(handle, dialect.MySQL)
+	m := new(Memory)
+	db.Use(Middleware(m))
+
+	const (
+		find   = "SELECT * FROM [user] WHERE [id] = ?"
+		update = "UPDATE [user] SET [name] = ? WHERE [id] = ?"
+	)
+	var users []user
+	db.Query(find, 1).All(&users)
+	db.Query(find, 2).All(&users)
+	db.Query(find, 3).One(new(user))
+	db.Query(update, "Eva", 3).Exec()
+	drv.SetErr(errors.New("lock wait timeout"))
+	db.Query(update, "Eva", 3).Exec()
+
+	tests := []struct {
+		template string
+		op       dali.Op
+		failed   bool
+		want     int
+	}{
+		{find, dali.OpAll, false, 2},
+		{find, dali.OpOne, false, 1},
+		{find, dali.OpExec, false, 0},
+		{update, dali.OpExec, false, 1},
+		{update, dali.OpExec, true, 1},
+	}
+	for _, tt := range tests {
+		if got := m.Count(tt.template, tt.op, tt.failed); got != tt.want {
+			t.Errorf("%s (%v, failed: %v): got %d, want %d",
+				tt.template, tt.op, tt.failed, got, tt.want)
+		}
+	}
+	if n := len(m.Durations(find, dali.OpAll)); n != 2 {
+		t.Errorf("got %d durations, want 2", n)
+	}
+
+	got := m.Observations()[4].LabelValues()
+	want := []string{update, "Exec", "error"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("labels: got %v, want %v", got, want)
+	}
+
+	m.Reset()
+	if obs := m.Observations(); len(obs) > 0 {
+		t.Errorf("got %d observations after reset", len(obs))
+	}
+
+	// Queries without a template are not labeled by their SQL.
+	drv.SetErr(nil)
+	Middleware(m)(handle).ExecContext(context.Background(), "DELETE FROM `session` WHERE `id` = 1")
+	o := m.Observations()[0]
+	if o.Template != "" || o.SQL != "DELETE FROM `session` WHERE `id` = 1" {
+		t.Errorf("got template %q, SQL %q", o.Template, o.SQL)
+	}
+	got = o.LabelValues()
+	want = []string{NoTemplate, "Exec", "ok"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("labels: got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,193 @@
+// Package dalireplay records queries executed through dali together
+// with their results, and replays them later without a database.
+//
+// Interactions are captured once against a real database:
+//
+//	rec := dalireplay.NewRecorder()
+//	db.Use(rec.Middleware())
+//	// Run the code using db.
+//	err := rec.WriteFile("testdata/users.json")
+//
+// and served back deterministically, e.g. in CI:
+//
+//	rep, err := dalireplay.Load("testdata/users.json")
+//	db := rep.DB(dialect.MySQL)
+//	// Run the code using db.
+//
+// Errors are replayed by their messages only, so errors.Is or
+// functions like dali.IsDuplicateKey don't recognize them.
+package dalireplay
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/mibk/dali"
+)
+
+// Interaction is a recorded query together with its result.
+type Interaction struct {
+	Query string
+	Args  []driver.Value
+
+	// Columns and Rows hold the result of a query returning rows.
+	Columns []string
+	Rows    [][]driver.Value
+
+	// LastInsertID and RowsAffected hold the result of an executed
+	// statement. They are zero if the driver doesn't support them.
+	LastInsertID int64
+	RowsAffected int64
+
+	// Err is the message of the error the query failed with, if any.
+	Err string
+}
+
+func (in *Interaction) err() error {
+	if in.Err == "" {
+		return nil
+	}
+	return errors.New(in.Err)
+}
+
+// Recorder records queries executed through its middleware.
+// It is safe for concurrent use.
+type Recorder struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	db           *sql.DB // serves recorded results
+}
+
+// NewRecorder returns a new Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{db: sql.OpenDB(connector{})}
+}
+
+// Middleware returns a dali.Middleware recording all queries that
+// pass through it. The rows returned by the wrapped Execer are read
+// in full and served from memory. QueryRowContext is therefore
+// executed as QueryContext by the wrapped Execer.
+func (r *Recorder) Middleware() dali.Middleware {
+	return func(e dali.Execer) dali.Execer {
+		return &recordingExecer{ex: e, r: r}
+	}
+}
+
+// Interactions returns the recorded interactions in the order
+// in which they happened.
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Interaction(nil), r.interactions...)
+}
+
+// WriteFile writes the recorded interactions to the named file as JSON.
+func (r *Recorder) WriteFile(name string) error {
+	b, err := json.MarshalIndent(r.Interactions(), "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, append(b, '\n'), 0o644)
+}
+
+func (r *Recorder) add(in *Interaction) {
+	r.mu.Lock()
+	r.interactions = append(r.interactions, *in)
+	r.mu.Unlock()
+}
+
+type recordingExecer struct {
+	ex dali.Execer
+	r  *Recorder
+}
+
+func (e *recordingExecer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	res, err := e.ex.ExecContext(ctx, query, args...)
+	in := &Interaction{Query: query, Args: convertArgs(args)}
+	if err != nil {
+		in.Err = err.Error()
+	} else {
+		in.LastInsertID, _ = res.LastInsertId()
+		in.RowsAffected, _ = res.RowsAffected()
+	}
+	e.r.add(in)
+	return res, err
+}
+
+func (e *recordingExecer) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	in := e.record(ctx, query, args)
+	if err := in.err(); err != nil {
+		return nil, err
+	}
+	return e.r.db.QueryContext(ctx, "", in)
+}
+
+func (e *recordingExecer) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	in := e.record(ctx, query, args)
+	return e.r.db.QueryRowContext(ctx, "", in)
+}
+
+func (e *recordingExecer) record(ctx context.Context, query string, args []interface{}) *Interaction {
+	in := &Interaction{Query: query, Args: convertArgs(args)}
+	if err := readRows(in, func() (*sql.Rows, error) {
+		return e.ex.QueryContext(ctx, query, args...)
+	}); err != nil {
+		in.Columns, in.Rows = nil, nil
+		in.Err = err.Error()
+	}
+	e.r.add(in)
+	return in
+}
+
+func readRows(in *Interaction, query func() (*sql.Rows, error)) error {
+	rows, err := query()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if in.Columns, err = rows.Columns(); err != nil {
+		return err
+	}
+	for rows.Next() {
+		vals := make([]interface{}, len(in.Columns))
+		dest := make([]interface{}, len(vals))
+		for i := range vals {
+			dest[i] = &vals[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		row := make([]driver.Value, len(vals))
+		for i, v := range vals {
+			row[i] = v
+		}
+		in.Rows = append(in.Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+// convertArgs converts args to values supported by drivers
+// so that they can be stored and compared.
+func convertArgs(args []interface{}) []driver.Value {
+	if len(args) == 0 {
+		return nil
+	}
+	vals := make([]driver.Value, len(args))
+	for i, a := range args {
+		v, err := driver.DefaultParameterConverter.ConvertValue(a)
+		if err != nil {
+			v = fmt.Sprint(a)
+		}
+		vals[i] = v
+	}
+	return vals
+}
@@ -0,0 +1,129 @@
+package dalireplay
+
+import (
+	"database/sql/driver"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mibk/dali"
+	"github.com/mibk/dali/dialect"
+	"github.com/mibk/dali/internal/testdriver"
+)
+
+type user struct {
+	ID      int64
+	Name    string
+	Created time.Time
+}
+
+type session struct {
+	Users    []user
+	Name     string
+	Inserted int64
+	Err      string
+	Deleted  int64
+}
+
+func run(t *testing.T, db *dali.DB) session {
+	t.Helper()
+	var s session
+	if err := db.Query("SELECT * FROM [user]").All(&s.Users); err != nil {
+		t.Fatal(err)
+	}
+	var u user
+	if err := db.Query("SELECT * FROM [user] WHERE [id] = ?", 1).One(&u); err != nil {
+		t.Fatal(err)
+	}
+	s.Name = u.Name
+	res, err := db.Query("INSERT INTO [user] ?values", dali.Map{"name": "Grace"}).Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Inserted, err = res.RowsAffected(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Query("SELECT [missing] FROM [user]").Rows(); err != nil {
+		s.Err = err.Error()
+	}
+	stmt, err := db.Prepare("DELETE FROM [user] WHERE [id] = ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = stmt.Bind(2).Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Deleted, err = res.RowsAffected(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	drv, sqldb := testdriver.New()
+	drv.SetRows([]string{"ID", "Name", "Created"},
+		[]driver.Value{int64(1), "Ada", created},
+		[]driver.Value{int64(2), []byte("Alan"), created},
+	)
+	drv.SetRowsAffected(1)
+	db := dali.NewDB(sqldb, dialect.MySQL)
+	rec := NewRecorder()
+	db.Use(rec.Middleware())
+
+	// The 4th query fails (the statement is prepared before Exec
+	// by testdriver).
+	drv.FailNext(nil, nil, nil, errors.New("Error 1054: Unknown column 'missing'"))
+	want := run(t, db)
+	if want.Err == "" {
+		t.Fatal("missing error")
+	}
+	if got := len(rec.Interactions()); got != 5 {
+		t.Errorf("got %d interactions, want 5", got)
+	}
+
+	name := filepath.Join(t.TempDir(), "session.json")
+	if err := rec.WriteFile(name); err != nil {
+		t.Fatal(err)
+	}
+	rep, err := Load(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := run(t, rep.DB(dialect.MySQL))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("replayed:\n got %+v\nwant %+v", got, want)
+	}
+	if rem := rep.Remaining(); len(rem) != 0 {
+		t.Errorf("not replayed: %v", rem)
+	}
+}
+
+func TestReplayerMismatch(t *testing.T) {
+	rep := NewReplayer([]Interaction{
+		{Query: "SELECT ?", Args: []driver.Value{int64(1)}, Columns: []string{"1"}, Rows: [][]driver.Value{{int64(1)}}},
+	})
+	db := rep.DB(dialect.MySQL)
+	var n int
+	err := db.Query("SELECT 2").ScanRow(&n)
+	if err == nil || !strings.Contains(err.Error(), "no recorded interaction for SELECT 2") {
+		t.Errorf("got %v, want no recorded interaction", err)
+	}
+	stmt, err := db.Prepare("SELECT ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stmt.Bind(2).ScanRow(&n); err == nil {
+		t.Error("replayed interaction with different args")
+	}
+	if err := stmt.Bind(1).ScanRow(&n); err != nil || n != 1 {
+		t.Errorf("got %d, %v; want 1", n, err)
+	}
+	if err := stmt.Bind(1).ScanRow(&n); err == nil {
+		t.Error("interaction replayed twice")
+	}
+}
@@ -0,0 +1,139 @@
+package dalireplay
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// jsonInteraction is the JSON representation of Interaction.
+type jsonInteraction struct {
+	Query        string         `json:"query"`
+	Args         []*jsonValue   `json:"args,omitempty"`
+	Columns      []string       `json:"columns,omitempty"`
+	Rows         [][]*jsonValue `json:"rows,omitempty"`
+	LastInsertID int64          `json:"lastInsertId,omitempty"`
+	RowsAffected int64          `json:"rowsAffected,omitempty"`
+	Err          string         `json:"error,omitempty"`
+}
+
+// jsonValue preserves the type of a driver.Value. A nil *jsonValue
+// represents NULL.
+type jsonValue struct {
+	Int64   *int64     `json:"int64,omitempty"`
+	Float64 *float64   `json:"float64,omitempty"`
+	Bool    *bool      `json:"bool,omitempty"`
+	Bytes   *[]byte    `json:"bytes,omitempty"`
+	String  *string    `json:"string,omitempty"`
+	Time    *time.Time `json:"time,omitempty"`
+}
+
+func newJSONValue(v driver.Value) (*jsonValue, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case int64:
+		return &jsonValue{Int64: &v}, nil
+	case float64:
+		return &jsonValue{Float64: &v}, nil
+	case bool:
+		return &jsonValue{Bool: &v}, nil
+	case []byte:
+		return &jsonValue{Bytes: &v}, nil
+	case string:
+		return &jsonValue{String: &v}, nil
+	case time.Time:
+		return &jsonValue{Time: &v}, nil
+	}
+	return nil, fmt.Errorf("dalireplay: unsupported value type %T", v)
+}
+
+func (v *jsonValue) value() driver.Value {
+	switch {
+	case v == nil:
+		return nil
+	case v.Int64 != nil:
+		return *v.Int64
+	case v.Float64 != nil:
+		return *v.Float64
+	case v.Bool != nil:
+		return *v.Bool
+	case v.Bytes != nil:
+		return *v.Bytes
+	case v.String != nil:
+		return *v.String
+	case v.Time != nil:
+		return *v.Time
+	}
+	return nil
+}
+
+func newJSONValues(vals []driver.Value) ([]*jsonValue, error) {
+	if vals == nil {
+		return nil, nil
+	}
+	jv := make([]*jsonValue, len(vals))
+	for i, v := range vals {
+		var err error
+		if jv[i], err = newJSONValue(v); err != nil {
+			return nil, err
+		}
+	}
+	return jv, nil
+}
+
+func values(jv []*jsonValue) []driver.Value {
+	if jv == nil {
+		return nil
+	}
+	vals := make([]driver.Value, len(jv))
+	for i, v := range jv {
+		vals[i] = v.value()
+	}
+	return vals
+}
+
+// MarshalJSON implements json.Marshaler. The types of the values
+// in Args and Rows are preserved.
+func (in Interaction) MarshalJSON() ([]byte, error) {
+	j := jsonInteraction{
+		Query:        in.Query,
+		Columns:      in.Columns,
+		LastInsertID: in.LastInsertID,
+		RowsAffected: in.RowsAffected,
+		Err:          in.Err,
+	}
+	var err error
+	if j.Args, err = newJSONValues(in.Args); err != nil {
+		return nil, err
+	}
+	for _, row := range in.Rows {
+		jrow, err := newJSONValues(row)
+		if err != nil {
+			return nil, err
+		}
+		j.Rows = append(j.Rows, jrow)
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (in *Interaction) UnmarshalJSON(b []byte) error {
+	var j jsonInteraction
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*in = Interaction{
+		Query:        j.Query,
+		Args:         values(j.Args),
+		Columns:      j.Columns,
+		LastInsertID: j.LastInsertID,
+		RowsAffected: j.RowsAffected,
+		Err:          j.Err,
+	}
+	for _, row := range j.Rows {
+		in.Rows = append(in.Rows, values(row))
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package dalireplay
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mibk/dali"
+	"github.com/mibk/dali/dialect"
+)
+
+// Replayer serves recorded interactions without a database.
+// A query is answered by the first interaction not replayed yet
+// that has the same query and args. Replayer implements dali.Execer.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	replayed     []bool
+	db           *sql.DB
+}
+
+// NewReplayer returns a Replayer serving interactions.
+func NewReplayer(interactions []Interaction) *Replayer {
+	p := &Replayer{
+		interactions: interactions,
+		replayed:     make([]bool, len(interactions)),
+	}
+	p.db = sql.OpenDB(connector{p})
+	return p
+}
+
+// Load returns a Replayer serving the interactions stored in the named
+// file by (*Recorder).WriteFile.
+func Load(name string) (*Replayer, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var interactions []Interaction
+	if err := json.Unmarshal(b, &interactions); err != nil {
+		return nil, fmt.Errorf("dalireplay: %s: %w", name, err)
+	}
+	return NewReplayer(interactions), nil
+}
+
+// DB returns a DB in the dialect d replaying the interactions.
+// Transactions are accepted, but have no effect.
+func (p *Replayer) DB(d dialect.Dialect) *dali.DB {
+	return dali.NewDB(p.db, d)
+}
+
+// Remaining returns the interactions that have not been replayed yet.
+func (p *Replayer) Remaining() []Interaction {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var rem []Interaction
+	for i, in := range p.interactions {
+		if !p.replayed[i] {
+			rem = append(rem, in)
+		}
+	}
+	return rem
+}
+
+// ExecContext implements dali.Execer.
+func (p *Replayer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.db.ExecContext(ctx, query, args...)
+}
+
+// QueryContext implements dali.Execer.
+func (p *Replayer) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return p.db.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext implements dali.Execer.
+func (p *Replayer) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return p.db.QueryRowContext(ctx, query, args...)
+}
+
+func (p *Replayer) lookup(query string, args []driver.NamedValue) (*Interaction, error) {
+	vals := make([]driver.Value, len(args))
+	for i, a := range args {
+		vals[i] = a.Value
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := range p.interactions {
+		in := &p.interactions[i]
+		if !p.replayed[i] && in.Query == query && valuesEqual(in.Args, vals) {
+			p.replayed[i] = true
+			return in, nil
+		}
+	}
+	if len(vals) > 0 {
+		return nil, fmt.Errorf("dalireplay: no recorded interaction for %s with args %v", query, vals)
+	}
+	return nil, fmt.Errorf("dalireplay: no recorded interaction for %s", query)
+}
+
+func valuesEqual(a, b []driver.Value) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		switch x := a[i].(type) {
+		case []byte:
+			y, ok := b[i].([]byte)
+			if !ok || !bytes.Equal(x, y) {
+				return false
+			}
+		case time.Time:
+			y, ok := b[i].(time.Time)
+			if !ok || !x.Equal(y) {
+				return false
+			}
+		default:
+			if a[i] != b[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// connector opens connections serving interactions. Interactions
+// passed as the only arg are served directly (this is how Recorder
+// serves the rows it has read); other queries are looked up in p.
+type connector struct {
+	p *Replayer
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) { return conn(c), nil }
+func (c connector) Driver() driver.Driver                        { return c }
+func (c connector) Open(string) (driver.Conn, error)             { return conn(c), nil }
+
+type conn struct {
+	p *Replayer
+}
+
+func (c conn) Prepare(query string) (driver.Stmt, error) { return stmt{c, query}, nil }
+func (c conn) Close() error                              { return nil }
+func (c conn) Begin() (driver.Tx, error)                 { return c, nil }
+func (c conn) Commit() error                             { return nil }
+func (c conn) Rollback() error                           { return nil }
+
+// CheckNamedValue passes interactions through unchanged
+// and converts other values as usual.
+func (c conn) CheckNamedValue(v *driver.NamedValue) error {
+	if _, ok := v.Value.(*Interaction); ok {
+		return nil
+	}
+	return driver.ErrSkip
+}
+
+func (c conn) interaction(query string, args []driver.NamedValue) (*Interaction, error) {
+	if len(args) == 1 {
+		if in, ok := args[0].Value.(*Interaction); ok {
+			return in, nil
+		}
+	}
+	if c.p == nil {
+		return nil, fmt.Errorf("dalireplay: no recorded interaction for %s", query)
+	}
+	return c.p.lookup(query, args)
+}
+
+func (c conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	in, err := c.interaction(query, args)
+	if err != nil {
+		return nil, err
+	}
+	if err := in.err(); err != nil {
+		return nil, err
+	}
+	return result{in.LastInsertID, in.RowsAffected}, nil
+}
+
+func (c conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	in, err := c.interaction(query, args)
+	if err != nil {
+		return nil, err
+	}
+	if err := in.err(); err != nil {
+		return nil, err
+	}
+	return &rows{in: in}, nil
+}
+
+type stmt struct {
+	c     conn
+	query string
+}
+
+func (s stmt) Close() error  { return nil }
+func (s stmt) NumInput() int { return -1 }
+
+func (s stmt) Exec(args []driver.Value) (driver.Result, error) {
+	panic("dalireplay: not reached")
+}
+
+func (s stmt) Query(args []driver.Value) (driver.Rows, error) {
+	panic("dalireplay: not reached")
+}
+
+func (s stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.c.ExecContext(ctx, s.query, args)
+}
+
+func (s stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.c.QueryContext(ctx, s.query, args)
+}
+
+type result struct {
+	lastInsertID, rowsAffected int64
+}
+
+func (r result) LastInsertId() (int64, error) { return r.lastInsertID, nil }
+func (r result) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+
+type rows struct {
+	in *Interaction
+	i  int
+}
+
+func (r *rows) Columns() []string { return r.in.Columns }
+func (r *rows) Close() error      { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.i >= len(r.in.Rows) {
+		return io.EOF
+	}
+	copy(dest, r.in.Rows[r.i])
+	r.i++
+	return nil
+}
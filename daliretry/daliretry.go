@@ -0,0 +1,154 @@
+// Package daliretry provides a dali middleware retrying queries that
+// failed due to transient errors, such as a connection lost during
+// a failover.
+//
+//	db.Use(daliretry.Middleware(nil))
+//
+// Queries returning rows are retried, but Exec is retried only if the
+// query is marked as idempotent:
+//
+//	db.Query(`DELETE FROM [session] WHERE [id] = ?`, id).Idempotent().Exec()
+package daliretry
+
+import (
+	"context"
+	"database/sql"
+	"math/rand"
+	"time"
+
+	"github.com/mibk/dali"
+	"github.com/mibk/dali/dialect"
+)
+
+// Options configure the retrying middleware. A nil *Options is
+// equivalent to the zero value.
+type Options struct {
+	// MaxAttempts is the maximum number of attempts to execute
+	// a query, including the first one. Defaults to 3.
+	MaxAttempts int
+
+	// BaseDelay is the upper bound of the delay before the first retry.
+	// It doubles with each subsequent retry up to MaxDelay; the actual
+	// delay is chosen randomly up to that bound. Defaults to 50ms.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the upper bound of the delay. Defaults to 1s.
+	MaxDelay time.Duration
+
+	// IsTransient reports whether a query failing with err,
+	// returned by a database of the dialect d, should be retried.
+	// Defaults to IsTransient.
+	IsTransient func(d dialect.Dialect, err error) bool
+}
+
+func (o *Options) withDefaults() Options {
+	var opts Options
+	if o != nil {
+		opts = *o
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 3
+	}
+	if opts.BaseDelay <= 0 {
+		opts.BaseDelay = 50 * time.Millisecond
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = time.Second
+	}
+	if opts.IsTransient == nil {
+		opts.IsTransient = IsTransient
+	}
+	return opts
+}
+
+// Middleware returns a dali.Middleware retrying queries that failed
+// with a transient error. Queries executed within transactions are
+// never retried, as a failure may leave the transaction unusable.
+func Middleware(opts *Options) dali.Middleware {
+	o := opts.withDefaults()
+	return func(e dali.Execer) dali.Execer {
+		return &execer{ex: e, opts: o}
+	}
+}
+
+type execer struct {
+	ex   dali.Execer
+	opts Options
+}
+
+func (e *execer) ExecContext(ctx context.Context, query string, args ...interface{}) (res sql.Result, err error) {
+	ev, ok := dali.QueryEventFromContext(ctx)
+	idempotent := ok && ev.Idempotent
+	e.retry(ctx, idempotent, func() error {
+		res, err = e.ex.ExecContext(ctx, query, args...)
+		return err
+	})
+	return res, err
+}
+
+func (e *execer) QueryContext(ctx context.Context, query string, args ...interface{}) (rows *sql.Rows, err error) {
+	e.retry(ctx, true, func() error {
+		rows, err = e.ex.QueryContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
+
+func (e *execer) QueryRowContext(ctx context.Context, query string, args ...interface{}) (row *sql.Row) {
+	e.retry(ctx, true, func() error {
+		row = e.ex.QueryRowContext(ctx, query, args...)
+		return row.Err()
+	})
+	return row
+}
+
+// retry calls f until it succeeds, fails with an error that is not
+// transient, or the attempts are exhausted. If retryable is false,
+// f is called just once.
+func (e *execer) retry(ctx context.Context, retryable bool, f func() error) {
+	var d dialect.Dialect
+	if ev, ok := dali.QueryEventFromContext(ctx); ok {
+		d = ev.Dialect
+		if ev.Tx != nil {
+			retryable = false
+		}
+	}
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil || !retryable || attempt >= e.opts.MaxAttempts || !e.opts.IsTransient(d, err) {
+			return
+		}
+		t := time.NewTimer(e.delay(attempt))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// delay returns a random delay before the retry following
+// the given attempt.
+func (e *execer) delay(attempt int) time.Duration {
+	max := e.opts.BaseDelay
+	for i := 1; i < attempt && max < e.opts.MaxDelay; i++ {
+		max *= 2
+	}
+	if max > e.opts.MaxDelay {
+		max = e.opts.MaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(max) + 1))
+}
+
+// IsTransient reports whether err is likely to be transient, i.e. whether
+// repeating the query that failed with it is likely to succeed. These
+// are lost connections and lock wait timeouts. The error is classified
+// using d (see dali.ClassifyError).
+func IsTransient(d dialect.Dialect, err error) bool {
+	switch dali.ClassifyError(d, err) {
+	case dialect.ConnectionError, dialect.LockTimeout:
+		return true
+	}
+	return false
+}
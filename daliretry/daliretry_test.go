@@ -0,0 +1,117 @@
+package daliretry
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/mibk/dali"
+	"github.com/mibk/dali/dialect"
+	"github.com/mibk/dali/internal/testdriver"
+)
+
+var (
+	errGone     = mysql.ErrInvalidConn
+	errDupEntry = &mysql.MySQLError{Number: 1062, Message: "Duplicate entry '1' for key 'PRIMARY'"}
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures []error
+		run      func(db *dali.DB) error
+		wantErr  error
+		attempts int
+	}{
+		{"rows", []error{errGone, driver.ErrBadConn},
+			func(db *dali.DB) error { _, err := db.Query("SELECT 1").Rows(); return err },
+			nil, 3},
+		{"scan row", []error{errGone},
+			func(db *dali.DB) error { return db.Query("SELECT 1").ScanRow() },
+			sql.ErrNoRows, 2},
+		{"exhausted", []error{errGone, errGone, errGone, errGone},
+			func(db *dali.DB) error { _, err := db.Query("SELECT 1").Rows(); return err },
+			errGone, 3},
+		{"not transient", []error{errDupEntry},
+			func(db *dali.DB) error { _, err := db.Query("SELECT 1").Rows(); return err },
+			errDupEntry, 1},
+		{"exec", []error{errGone},
+			func(db *dali.DB) error { _, err := db.Query("DELETE 1").Exec(); return err },
+			errGone, 1},
+		{"idempotent exec", []error{errGone},
+			func(db *dali.DB) error { _, err := db.Query("DELETE 1").Idempotent().Exec(); return err },
+			nil, 2},
+		{"tx", []error{errGone},
+			func(db *dali.DB) error {
+				tx, err := db.Begin()
+				if err != nil {
+					return err
+				}
+				_, err = tx.Query("SELECT 1").Rows()
+				return err
+			},
+			errGone, 1},
+	}
+
+	for _, tt := range tests {
+		drv, handle := testdriver.New()
+		db := dali.NewDB(handle, dialect.MySQL)
+		db.Use(Middleware(&Options{BaseDelay: time.Millisecond}))
+		drv.FailNext(tt.failures...)
+
+		err := tt.run(db)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: got err %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if n := len(drv.Queries()); n != tt.attempts {
+			t.Errorf("%s: got %d attempts, want %d", tt.name, n, tt.attempts)
+		}
+	}
+}
+
+func TestMiddlewareCanceled(t *testing.T) {
+	drv, handle := testdriver.New()
+	db := dali.NewDB(handle, dialect.MySQL)
+	db.Use(Middleware(&Options{BaseDelay: time.Hour, MaxDelay: time.Hour}))
+	drv.FailNext(errGone, errGone)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := db.QueryWithContext(ctx, "SELECT 1").Rows(); err != errGone {
+		t.Errorf("got err %v, want %v", err, errGone)
+	}
+	if n := len(drv.Queries()); n != 1 {
+		t.Errorf("got %d attempts, want 1", n)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		d    dialect.Dialect
+		err  error
+		want bool
+	}{
+		{dialect.MySQL, nil, false},
+		{dialect.MySQL, driver.ErrBadConn, true},
+		{nil, driver.ErrBadConn, true},
+		{dialect.MySQL, fmt.Errorf("read: %w", syscall.ECONNRESET), true},
+		{dialect.MySQL, errGone, true},
+		{dialect.MySQL, &mysql.MySQLError{Number: 1205}, true},
+		{nil, &mysql.MySQLError{Number: 1205}, false},
+		{dialect.MySQL, errors.New("Error 1205 (HY000): Lock wait timeout exceeded"), false},
+		{dialect.MySQL, errDupEntry, false},
+		{dialect.MySQL, context.Canceled, false},
+	}
+	for _, tt := range tests {
+		if got := IsTransient(tt.d, tt.err); got != tt.want {
+			t.Errorf("%v (%T): got %v, want %v", tt.err, tt.d, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// Package dalislog provides a dali middleware logging every executed
+// query using log/slog.
+//
+//	db.Use(dalislog.Middleware(slog.Default(), &dalislog.Options{
+//		Redact: true,
+//	}))
+package dalislog
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/mibk/dali"
+)
+
+// Options configure the logging middleware. A nil *Options is
+// equivalent to the zero value.
+type Options struct {
+	// Level is the level of records logged for successful queries.
+	// Failed queries are always logged with slog.LevelError.
+	// Defaults to slog.LevelDebug.
+	Level slog.Leveler
+
+	// Redact prevents argument values from appearing in the log.
+	// If set, the query template is logged instead of the translated
+	// SQL (which has the values interpolated) and the bound args are
+	// omitted.
+	Redact bool
+}
+
+// Middleware returns a dali.Middleware logging every query using
+// logger. Each record contains the query, the operation, the duration
+// and the error (if any); records of executed statements also contain
+// the number of rows affected.
+func Middleware(logger *slog.Logger, opts *Options) dali.Middleware {
+	if opts == nil {
+		opts = new(Options)
+	}
+	level := opts.Level
+	if level == nil {
+		level = slog.LevelDebug
+	}
+	return func(e dali.Execer) dali.Execer {
+		return &execer{
+			ex:     e,
+			logger: logger,
+			level:  level,
+			redact: opts.Redact,
+		}
+	}
+}
+
+type execer struct {
+	ex     dali.Execer
+	logger *slog.Logger
+	level  slog.Leveler
+	redact bool
+}
+
+func (e *execer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	res, err := e.ex.ExecContext(ctx, query, args...)
+	var attrs []slog.Attr
+	if err == nil {
+		if n, err := res.RowsAffected(); err == nil {
+			attrs = append(attrs, slog.Int64("rows_affected", n))
+		}
+	}
+	e.log(ctx, query, args, time.Since(start), err, attrs...)
+	return res, err
+}
+
+func (e *execer) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := e.ex.QueryContext(ctx, query, args...)
+	e.log(ctx, query, args, time.Since(start), err)
+	return rows, err
+}
+
+func (e *execer) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := e.ex.QueryRowContext(ctx, query, args...)
+	e.log(ctx, query, args, time.Since(start), row.Err())
+	return row
+}
+
+func (e *execer) log(ctx context.Context, query string, args []interface{}, d time.Duration, err error, extra ...slog.Attr) {
+	level := e.level.Level()
+	if err != nil {
+		level = slog.LevelError
+	}
+	if !e.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := make([]slog.Attr, 0, 6+len(extra))
+	ev, ok := dali.QueryEventFromContext(ctx)
+	switch {
+	case e.redact && ok:
+		attrs = append(attrs, slog.String("template", ev.Template))
+	case e.redact:
+		// The template is unknown, so the query
+		// may contain interpolated values.
+		attrs = append(attrs, slog.String("sql", "<redacted>"))
+	default:
+		attrs = append(attrs, slog.String("sql", query))
+		if len(args) > 0 {
+			attrs = append(attrs, slog.Any("args", args))
+		}
+	}
+	if ok {
+		attrs = append(attrs, slog.String("op", ev.Op.String()))
+		if ev.Prepared {
+			attrs = append(attrs, slog.Bool("prepared", true))
+		}
+		if ev.Tx != nil {
+			attrs = append(attrs, slog.Bool("tx", true))
+		}
+	}
+	attrs = append(attrs, slog.Duration("duration", d))
+	attrs = append(attrs, extra...)
+	if err != nil {
+		attrs = append(attrs, slog.Any("err", err))
+	}
+	e.logger.LogAttrs(ctx, level, "query", attrs...)
+}
@@ -0,0 +1,144 @@
+package dalislog
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/mibk/dali"
+	"github.com/mibk/dali/dialect"
+	"github.com/mibk/dali/internal/testdriver"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		opts *Options
+		run  func(db *dali.DB) error
+		want map[string]interface{}
+	}{
+		{
+			nil,
+			func(db *dali.DB) error {
+				_, err := db.Query("UPDATE [user] SET [name] = ?", "Lucie").Exec()
+				return err
+			},
+			map[string]interface{}{
+				"level":         "DEBUG",
+				"msg":           "query",
+				"sql":           "UPDATE `user` SET `name` = 'Lucie'",
+				"op":            "Exec",
+				"rows_affected": 3.0,
+			},
+		},
+		{
+			&Options{Level: slog.LevelInfo, Redact: true},
+			func(db *dali.DB) error {
+				var name string
+				return db.Query("SELECT [name] FROM [user] WHERE [id] = ?", 4).ScanRow(&name)
+			},
+			map[string]interface{}{
+				"level":    "INFO",
+				"msg":      "query",
+				"template": "SELECT [name] FROM [user] WHERE [id] = ?",
+				"op":       "ScanRow",
+			},
+		},
+		{
+			&Options{Redact: true},
+			func(db *dali.DB) error {
+				stmt, err := db.Prepare("SELECT [name] FROM [user] WHERE [id] = ?")
+				if err != nil {
+					return err
+				}
+				_, err = stmt.Bind(4).Rows()
+				return err
+			},
+			map[string]interface{}{
+				"level":    "DEBUG",
+				"msg":      "query",
+				"template": "SELECT [name] FROM [user] WHERE [id] = ?",
+				"op":       "Rows",
+				"prepared": true,
+			},
+		},
+		{
+			nil,
+			func(db *dali.DB) error {
+				tx, err := db.Begin()
+				if err != nil {
+					return err
+				}
+				_, err = tx.Query("SELECT ?", 1).Rows()
+				return err
+			},
+			map[string]interface{}{
+				"level": "DEBUG",
+				"msg":   "query",
+				"sql":   "SELECT 1",
+				"op":    "Rows",
+				"tx":    true,
+			},
+		},
+	}
+
+	for i, tt := range tests {
+		drv, handle := testdriver.New()
+		drv.SetRowsAffected(3)
+		drv.SetRows([]string{"name"})
+		db := dali.NewDB(handle, dialect.MySQL)
+		buf := new(bytes.Buffer)
+		logger := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		db.Use(Middleware(logger, tt.opts))
+
+		if err := tt.run(db); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("#%d: unexpected err: %v", i, err)
+		}
+		got := decode(t, buf)
+		delete(got, "time")
+		if _, ok := got["duration"]; !ok {
+			t.Errorf("#%d: duration missing", i)
+		}
+		delete(got, "duration")
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("#%d:\n got: %v\nwant: %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestMiddlewareError(t *testing.T) {
+	drv, handle := testdriver.New()
+	drv.SetErr(errors.New("table doesn't exist"))
+	db := dali.NewDB(handle, dialect.MySQL)
+	buf := new(bytes.Buffer)
+	db.Use(Middleware(slog.New(slog.NewJSONHandler(buf, nil)), &Options{Level: slog.LevelDebug}))
+
+	db.Query("SELECT * FROM ?ident", "post").Exec()
+	got := decode(t, buf)
+	if got["level"] != "ERROR" || got["err"] != "table doesn't exist" {
+		t.Errorf("got %v, want the error logged", got)
+	}
+}
+
+func TestMiddlewareDisabled(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := slog.New(slog.NewJSONHandler(buf, nil))
+	e := Middleware(logger, nil)(nil)
+	e.(*execer).log(context.Background(), "SELECT 1", nil, 0, nil)
+	if buf.Len() > 0 {
+		t.Errorf("debug record logged with info level handler: %s", buf)
+	}
+}
+
+func decode(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	t.Helper()
+	var m map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatalf("decode %q: %v", buf, err)
+	}
+	return m
+}
@@ -0,0 +1,221 @@
+// Package dalislow provides a dali middleware detecting slow queries.
+//
+//	db.Use(dalislow.Middleware(dalislow.Options{
+//		Threshold: 200 * time.Millisecond,
+//		Explain:   true,
+//		Report: func(ctx context.Context, r *dalislow.Report) {
+//			log.Printf("slow query (%v): %s\n%v", r.Duration, r.SQL, r.Plan)
+//		},
+//	}))
+package dalislow
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mibk/dali"
+)
+
+// Options configure the slow query detector.
+type Options struct {
+	// Threshold is the duration a query must exceed to be
+	// considered slow.
+	Threshold time.Duration
+
+	// Explain enables running EXPLAIN for slow SELECT queries.
+	// It is not run for prepared statements, and for queries within
+	// transactions or on a dali.Conn.
+	//
+	// The EXPLAIN statement is executed by the Execer wrapped by
+	// the middleware, so it is routed like the query (e.g. to
+	// a replica of a dali.Cluster), and it is seen by the middlewares
+	// added after this one. It cannot run on the connection that
+	// executed the query, which stays busy until the rows are read,
+	// so it runs in a separate goroutine on another connection of
+	// the pool, and explained queries are reported once the plan
+	// is known.
+	Explain bool
+
+	// MaxExplains limits the number of EXPLAIN statements running
+	// at the same time. A slow query exceeding the limit is reported
+	// right away with PlanErr set to ErrExplainSkipped. Defaults to 1.
+	MaxExplains int
+
+	// ExplainTimeout limits waiting for a connection and running
+	// EXPLAIN. Defaults to 5 seconds.
+	ExplainTimeout time.Duration
+
+	// Report is called for every slow query. It may be called
+	// concurrently if Explain is set. For explained queries, ctx
+	// is not canceled together with the context of the query.
+	Report func(ctx context.Context, r *Report)
+}
+
+// ErrExplainSkipped is reported as Report.PlanErr if the query
+// was not explained because of Options.MaxExplains.
+var ErrExplainSkipped = errors.New("dalislow: too many EXPLAIN statements running")
+
+// Report describes a slow query.
+type Report struct {
+	// SQL and Args are the query passed to the Execer.
+	SQL  string
+	Args []interface{}
+
+	// Event describes the query in more detail. It is nil if the
+	// query was not executed by a dali.Query.
+	Event *dali.QueryEvent
+
+	// Duration is the time it took to execute the query. For queries
+	// returning rows, it doesn't include the time spent reading them.
+	Duration time.Duration
+
+	// Err is the error returned by the query, if any.
+	Err error
+
+	// Plan is the result of EXPLAIN of the query, or nil, if
+	// the query was not explained. PlanErr is the error returned
+	// by EXPLAIN, if any.
+	Plan    *Plan
+	PlanErr error
+}
+
+// Plan is a query plan as returned by EXPLAIN.
+type Plan struct {
+	Columns []string
+	Rows    [][]string // NULL values are represented as "NULL"
+}
+
+func (p *Plan) String() string {
+	b := new(strings.Builder)
+	b.WriteString(strings.Join(p.Columns, "\t"))
+	for _, row := range p.Rows {
+		b.WriteByte('\n')
+		b.WriteString(strings.Join(row, "\t"))
+	}
+	return b.String()
+}
+
+// Middleware returns a dali.Middleware reporting queries that take
+// longer than opts.Threshold.
+func Middleware(opts Options) dali.Middleware {
+	if opts.Report == nil {
+		panic("dalislow: Report func not set")
+	}
+	if opts.MaxExplains <= 0 {
+		opts.MaxExplains = 1
+	}
+	if opts.ExplainTimeout <= 0 {
+		opts.ExplainTimeout = 5 * time.Second
+	}
+	explains := make(chan struct{}, opts.MaxExplains)
+	return func(e dali.Execer) dali.Execer {
+		return &execer{ex: e, opts: opts, explains: explains}
+	}
+}
+
+type execer struct {
+	ex       dali.Execer
+	opts     Options
+	explains chan struct{} // semaphore limiting running EXPLAINs
+}
+
+func (e *execer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	res, err := e.ex.ExecContext(ctx, query, args...)
+	e.check(ctx, query, args, time.Since(start), err)
+	return res, err
+}
+
+func (e *execer) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := e.ex.QueryContext(ctx, query, args...)
+	e.check(ctx, query, args, time.Since(start), err)
+	return rows, err
+}
+
+func (e *execer) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := e.ex.QueryRowContext(ctx, query, args...)
+	e.check(ctx, query, args, time.Since(start), row.Err())
+	return row
+}
+
+func (e *execer) check(ctx context.Context, query string, args []interface{}, d time.Duration, err error) {
+	if d <= e.opts.Threshold {
+		return
+	}
+	r := &Report{
+		SQL:      query,
+		Args:     args,
+		Duration: d,
+		Err:      err,
+	}
+	r.Event, _ = dali.QueryEventFromContext(ctx)
+	if e.opts.Explain && err == nil && explainable(r.Event, query) {
+		select {
+		case e.explains <- struct{}{}:
+		default:
+			r.PlanErr = ErrExplainSkipped
+			e.opts.Report(ctx, r)
+			return
+		}
+		// The context keeps its values, which may affect
+		// the routing, but the query may have already been
+		// canceled.
+		ctx = context.WithoutCancel(ctx)
+		go func() {
+			r.Plan, r.PlanErr = e.explain(ctx, query, args)
+			<-e.explains
+			e.opts.Report(ctx, r)
+		}()
+		return
+	}
+	e.opts.Report(ctx, r)
+}
+
+func explainable(ev *dali.QueryEvent, query string) bool {
+	if ev == nil || ev.Prepared || ev.Tx != nil || ev.Conn != nil {
+		return false
+	}
+	query = strings.TrimLeft(query, " \t\r\n(")
+	return len(query) >= 6 && strings.EqualFold(query[:6], "SELECT")
+}
+
+// explain runs EXPLAIN of query using the wrapped Execer.
+func (e *execer) explain(ctx context.Context, query string, args []interface{}) (*Plan, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.opts.ExplainTimeout)
+	defer cancel()
+	rows, err := e.ex.QueryContext(ctx, "EXPLAIN "+query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	p := &Plan{Columns: cols}
+	vals := make([]sql.NullString, len(cols))
+	dest := make([]interface{}, len(cols))
+	for i := range vals {
+		dest[i] = &vals[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("dalislow: scan plan: %v", err)
+		}
+		row := make([]string, len(vals))
+		for i, v := range vals {
+			row[i] = "NULL"
+			if v.Valid {
+				row[i] = v.String
+			}
+		}
+		p.Rows = append(p.Rows, row)
+	}
+	return p, rows.Err()
+}
@@ -0,0 +1,181 @@
+package dalislow
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mibk/dali"
+	"github.com/mibk/dali/dialect"
+	"github.com/mibk/dali/internal/testdriver"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		threshold time.Duration
+		run       func(db *dali.DB)
+		reported  bool
+		explained bool
+	}{
+		{0, func(db *dali.DB) { db.Query("SELECT [id] FROM [user]").Rows() }, true, true},
+		{0, func(db *dali.DB) { db.Query("  (select 1)").ScanRow(new(int)) }, true, true},
+		{0, func(db *dali.DB) { db.Query("DELETE FROM [user]").Exec() }, true, false},
+		{0, func(db *dali.DB) { mustPrepare(db, "SELECT ?").Bind(1).Rows() }, true, false},
+		{0, func(db *dali.DB) { mustBegin(db).Query("SELECT 1").Rows() }, true, false},
+		{0, func(db *dali.DB) { mustConn(db).Query("SELECT 1").Rows() }, true, false},
+		{time.Hour, func(db *dali.DB) { db.Query("SELECT 1").Rows() }, false, false},
+	}
+
+	for i, tt := range tests {
+		drv, handle := testdriver.New()
+		drv.SetRows([]string{"id", "key"}, []driver.Value{int64(1), nil})
+		db := dali.NewDB(handle, dialect.MySQL)
+		reports := make(chan *Report, 1)
+		db.Use(Middleware(Options{
+			Threshold: tt.threshold,
+			Explain:   true,
+			Report: func(ctx context.Context, r *Report) {
+				reports <- r
+			},
+		}))
+		tt.run(db)
+
+		if !tt.reported {
+			select {
+			case r := <-reports:
+				t.Errorf("#%d: unexpected report: %+v", i, r)
+			default:
+			}
+			continue
+		}
+		r := wait(t, reports)
+		if r.Event == nil {
+			t.Errorf("#%d: event missing", i)
+		}
+		if !tt.explained {
+			if r.Plan != nil {
+				t.Errorf("#%d: unexpected plan: %v", i, r.Plan)
+			}
+			continue
+		}
+		if r.PlanErr != nil {
+			t.Fatalf("#%d: unexpected plan err: %v", i, r.PlanErr)
+		}
+		want := &Plan{Columns: []string{"id", "key"}, Rows: [][]string{{"1", "NULL"}}}
+		if !reflect.DeepEqual(r.Plan, want) {
+			t.Errorf("#%d:\n got: %v\nwant: %v", i, r.Plan, want)
+		}
+		queries := drv.Queries()
+		if got, want := queries[len(queries)-1], "EXPLAIN "+r.SQL; got != want {
+			t.Errorf("#%d:\n got: %v\nwant: %v", i, got, want)
+		}
+	}
+}
+
+func TestExplainSingleConn(t *testing.T) {
+	drv, handle := testdriver.New()
+	drv.SetRows([]string{"id"}, []driver.Value{int64(1)})
+	handle.SetMaxOpenConns(1)
+	db := dali.NewDB(handle, dialect.MySQL)
+	reports := make(chan *Report, 1)
+	db.Use(Middleware(Options{
+		Explain: true,
+		Report: func(ctx context.Context, r *Report) {
+			reports <- r
+		},
+	}))
+
+	rows, err := db.Query("SELECT [id] FROM [user]").Rows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case r := <-reports:
+		t.Fatalf("reported before the rows were closed: %+v", r)
+	case <-time.After(10 * time.Millisecond):
+	}
+	rows.Close()
+
+	r := wait(t, reports)
+	if r.PlanErr != nil {
+		t.Fatalf("unexpected plan err: %v", r.PlanErr)
+	}
+	if r.Plan == nil || len(r.Plan.Rows) != 1 {
+		t.Errorf("unexpected plan: %v", r.Plan)
+	}
+	if r.Event == nil || r.Event.SQL != r.SQL {
+		t.Errorf("unexpected event: %+v", r.Event)
+	}
+}
+
+func TestMaxExplains(t *testing.T) {
+	reports := make(chan *Report, 2)
+	mw := Middleware(Options{
+		Explain: true,
+		Report: func(ctx context.Context, r *Report) {
+			reports <- r
+		},
+	})
+	var dbs [2]*dali.DB
+	for i := range dbs {
+		drv, handle := testdriver.New()
+		drv.SetRows([]string{"id"}, []driver.Value{int64(1)})
+		handle.SetMaxOpenConns(1)
+		dbs[i] = dali.NewDB(handle, dialect.MySQL)
+		dbs[i].Use(mw)
+	}
+
+	// The EXPLAIN waits for the connection held by the rows.
+	rows, err := dbs[0].Query("SELECT [id] FROM [user]").Rows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbs[1].Query("SELECT [id] FROM [user]").Rows(); err != nil {
+		t.Fatal(err)
+	}
+	r := wait(t, reports)
+	if r.PlanErr != ErrExplainSkipped || r.Plan != nil {
+		t.Errorf("got plan %v, err %v, want %v", r.Plan, r.PlanErr, ErrExplainSkipped)
+	}
+	rows.Close()
+	if r := wait(t, reports); r.PlanErr != nil || r.Plan == nil {
+		t.Errorf("got plan %v, err %v, want plan", r.Plan, r.PlanErr)
+	}
+}
+
+func wait(t *testing.T, reports <-chan *Report) *Report {
+	t.Helper()
+	select {
+	case r := <-reports:
+		return r
+	case <-time.After(time.Second):
+		t.Fatal("slow query not reported")
+		return nil
+	}
+}
+
+func mustPrepare(db *dali.DB, query string) *dali.Stmt {
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		panic(err)
+	}
+	return stmt
+}
+
+func mustConn(db *dali.DB) *dali.Conn {
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		panic(err)
+	}
+	return conn
+}
+
+func mustBegin(db *dali.DB) *dali.Tx {
+	tx, err := db.Begin()
+	if err != nil {
+		panic(err)
+	}
+	return tx
+}
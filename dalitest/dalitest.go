@@ -0,0 +1,304 @@
+// Package dalitest provides a fake database for testing code using dali.
+// Tests declare the queries they expect, as translated by dali, together
+// with their results, and check that all of them were executed:
+//
+//	db, mock := dalitest.New(dialect.MySQL)
+//	mock.ExpectQuery("SELECT `name` FROM `user` WHERE `id` = 1").
+//		WillReturnRows(dalitest.NewRows("name").AddRow("Ada"))
+//	mock.ExpectExecRegexp("^DELETE FROM `session`").
+//		WillReturnResult(0, 3)
+//
+//	// Run the tested code using db.
+//
+//	if err := mock.ExpectationsWereMet(); err != nil {
+//		t.Error(err)
+//	}
+//
+// The queries are expected in the order in which they were declared.
+//
+// Alternatively, NewDryRun records the executed queries, which can be
+// compared with golden files using Golden. That way changes to SQL
+// derived from structs (e.g. using ?values or ?set) show up in diffs.
+package dalitest
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/mibk/dali"
+	"github.com/mibk/dali/dialect"
+	"github.com/mibk/dali/internal/testdriver"
+)
+
+// New returns a DB in the dialect d backed by a fake database driven
+// by the returned Mock.
+func New(d dialect.Dialect) (*dali.DB, *Mock) {
+	m := new(Mock)
+	return dali.NewDB(testdriver.Open(mockHandler{m}), d), m
+}
+
+// Mock holds the expectations of a fake database.
+type Mock struct {
+	mu   sync.Mutex
+	exps []*Expectation
+	errs []error
+}
+
+type kind int
+
+const (
+	kindExec kind = iota
+	kindQuery
+	kindBegin
+	kindCommit
+	kindRollback
+)
+
+var kindNames = [...]string{
+	kindExec:     "Exec",
+	kindQuery:    "Query",
+	kindBegin:    "Begin",
+	kindCommit:   "Commit",
+	kindRollback: "Rollback",
+}
+
+// Expectation is an expected query or a transaction operation.
+type Expectation struct {
+	kind  kind
+	sql   string
+	re    *regexp.Regexp
+	args  []driver.Value
+	check bool // whether to check args
+
+	result driver.Result
+	rows   *Rows
+	err    error
+	met    bool
+}
+
+func (m *Mock) expect(k kind, sql string, re *regexp.Regexp) *Expectation {
+	e := &Expectation{
+		kind:   k,
+		sql:    sql,
+		re:     re,
+		result: driver.RowsAffected(0),
+		rows:   NewRows(),
+	}
+	m.mu.Lock()
+	m.exps = append(m.exps, e)
+	m.mu.Unlock()
+	return e
+}
+
+// ExpectExec expects the execution of a statement exactly matching sql
+// (e.g. by Query.Exec).
+func (m *Mock) ExpectExec(sql string) *Expectation { return m.expect(kindExec, sql, nil) }
+
+// ExpectExecRegexp expects the execution of a statement matching
+// the regular expression pattern.
+func (m *Mock) ExpectExecRegexp(pattern string) *Expectation {
+	return m.expect(kindExec, "", regexp.MustCompile(pattern))
+}
+
+// ExpectQuery expects a query returning rows exactly matching sql
+// (e.g. by Query.One).
+func (m *Mock) ExpectQuery(sql string) *Expectation { return m.expect(kindQuery, sql, nil) }
+
+// ExpectQueryRegexp expects a query returning rows matching
+// the regular expression pattern.
+func (m *Mock) ExpectQueryRegexp(pattern string) *Expectation {
+	return m.expect(kindQuery, "", regexp.MustCompile(pattern))
+}
+
+// ExpectBegin expects the beginning of a transaction.
+func (m *Mock) ExpectBegin() *Expectation { return m.expect(kindBegin, "", nil) }
+
+// ExpectCommit expects a transaction to be committed.
+func (m *Mock) ExpectCommit() *Expectation { return m.expect(kindCommit, "", nil) }
+
+// ExpectRollback expects a transaction to be rolled back.
+func (m *Mock) ExpectRollback() *Expectation { return m.expect(kindRollback, "", nil) }
+
+// WithArgs sets the args the query is expected to be executed with.
+// As dali interpolates args into the SQL, only prepared statements
+// are executed with args.
+func (e *Expectation) WithArgs(args ...interface{}) *Expectation {
+	e.args = make([]driver.Value, len(args))
+	for i, a := range args {
+		e.args[i] = mustConvert(a)
+	}
+	e.check = true
+	return e
+}
+
+// WillReturnResult sets the result of the expected statement.
+func (e *Expectation) WillReturnResult(lastInsertID, rowsAffected int64) *Expectation {
+	e.result = result{lastInsertID, rowsAffected}
+	return e
+}
+
+// WillReturnRows sets the rows returned by the expected query.
+func (e *Expectation) WillReturnRows(rows *Rows) *Expectation {
+	e.rows = rows
+	return e
+}
+
+// WillReturnError makes the expected operation fail with err.
+func (e *Expectation) WillReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+func (e *Expectation) String() string {
+	var b strings.Builder
+	b.WriteString(kindNames[e.kind])
+	switch {
+	case e.re != nil:
+		fmt.Fprintf(&b, " matching %q", e.re)
+	case e.kind == kindExec || e.kind == kindQuery:
+		fmt.Fprintf(&b, " %q", e.sql)
+	}
+	if e.check {
+		fmt.Fprintf(&b, " with args %v", e.args)
+	}
+	return b.String()
+}
+
+func (e *Expectation) matches(k kind, query string, args []driver.Value) bool {
+	if e.kind != k {
+		return false
+	}
+	if k == kindExec || k == kindQuery {
+		if e.re != nil && !e.re.MatchString(query) || e.re == nil && e.sql != query {
+			return false
+		}
+	}
+	return !e.check || len(e.args) == 0 && len(args) == 0 || reflect.DeepEqual(e.args, args)
+}
+
+// match finds the next expectation and checks it matches the operation.
+func (m *Mock) match(k kind, query string, args []driver.Value) (*Expectation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	got := kindNames[k]
+	if k == kindExec || k == kindQuery {
+		got += fmt.Sprintf(" %q", query)
+	}
+	if len(args) > 0 {
+		got += fmt.Sprintf(" with args %v", args)
+	}
+	for _, e := range m.exps {
+		if e.met {
+			continue
+		}
+		if !e.matches(k, query, args) {
+			err := fmt.Errorf("dalitest: got %s, want %v", got, e)
+			m.errs = append(m.errs, err)
+			return nil, err
+		}
+		e.met = true
+		return e, nil
+	}
+	err := fmt.Errorf("dalitest: unexpected %s", got)
+	m.errs = append(m.errs, err)
+	return nil, err
+}
+
+// ExpectationsWereMet returns an error if some of the expectations
+// were not met or if there were unexpected operations.
+func (m *Mock) ExpectationsWereMet() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	errs := append([]error(nil), m.errs...)
+	for _, e := range m.exps {
+		if !e.met {
+			errs = append(errs, fmt.Errorf("dalitest: expected %v not executed", e))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Rows are rows returned by an expected query.
+type Rows struct {
+	cols []string
+	rows [][]driver.Value
+}
+
+// NewRows returns empty Rows with the given columns.
+func NewRows(cols ...string) *Rows {
+	return &Rows{cols: cols}
+}
+
+// AddRow adds a row of values, which must correspond to the columns.
+// It panics if vals cannot be converted to driver values.
+func (r *Rows) AddRow(vals ...interface{}) *Rows {
+	if len(vals) != len(r.cols) {
+		panic(fmt.Sprintf("dalitest: got %d values for %d columns", len(vals), len(r.cols)))
+	}
+	row := make([]driver.Value, len(vals))
+	for i, v := range vals {
+		row[i] = mustConvert(v)
+	}
+	r.rows = append(r.rows, row)
+	return r
+}
+
+func mustConvert(v interface{}) driver.Value {
+	dv, err := driver.DefaultParameterConverter.ConvertValue(v)
+	if err != nil {
+		panic(fmt.Sprintf("dalitest: %v", err))
+	}
+	return dv
+}
+
+type result struct {
+	lastInsertID, rowsAffected int64
+}
+
+func (r result) LastInsertId() (int64, error) { return r.lastInsertID, nil }
+func (r result) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+
+// mockHandler serves the operations of the fake database
+// by matching them with the expectations of m.
+type mockHandler struct {
+	m *Mock
+}
+
+func (h mockHandler) Begin() error    { return h.match(kindBegin) }
+func (h mockHandler) Commit() error   { return h.match(kindCommit) }
+func (h mockHandler) Rollback() error { return h.match(kindRollback) }
+
+func (h mockHandler) match(k kind) error {
+	e, err := h.m.match(k, "", nil)
+	if err != nil {
+		return err
+	}
+	return e.err
+}
+
+func (h mockHandler) Exec(query string, args []driver.Value) (driver.Result, error) {
+	e, err := h.m.match(kindExec, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	return e.result, nil
+}
+
+func (h mockHandler) Query(query string, args []driver.Value) (driver.Rows, error) {
+	e, err := h.m.match(kindQuery, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	return testdriver.NewRows(e.rows.cols, e.rows.rows), nil
+}
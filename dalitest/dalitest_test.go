@@ -0,0 +1,80 @@
+package dalitest
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mibk/dali"
+	"github.com/mibk/dali/dialect"
+)
+
+type user struct {
+	ID   int64 `db:"id,selectonly"`
+	Name string
+}
+
+func TestMock(t *testing.T) {
+	db, mock := New(dialect.MySQL)
+	mock.ExpectQuery("SELECT * FROM `user` WHERE `id` IN (1, 2)").
+		WillReturnRows(NewRows("id", "Name").AddRow(1, "Ada").AddRow(2, "Alan"))
+	mock.ExpectBegin()
+	mock.ExpectExecRegexp("^INSERT INTO `user`").WillReturnResult(3, 1)
+	mock.ExpectExec("SELECT ?").WithArgs(5).WillReturnError(errors.New("no"))
+	mock.ExpectCommit()
+
+	var users []user
+	if err := db.Query("SELECT * FROM [user] WHERE [id] IN (?...)", []int{1, 2}).All(&users); err != nil {
+		t.Fatal(err)
+	}
+	want := []user{{1, "Ada"}, {2, "Alan"}}
+	if !reflect.DeepEqual(users, want) {
+		t.Errorf("got %v, want %v", users, want)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := dali.LastInsertID(tx.Query("INSERT INTO [user] ?values", user{Name: "Grace"}).Exec())
+	if err != nil || id != 3 {
+		t.Errorf("got id %d, err %v; want 3, nil", id, err)
+	}
+	stmt, err := tx.Prepare("SELECT ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stmt.Bind(5).Exec(); err == nil || err.Error() != "no" {
+		t.Errorf("got err %v, want no", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMockUnmet(t *testing.T) {
+	db, mock := New(dialect.MySQL)
+	mock.ExpectExec("DELETE FROM `user`")
+	mock.ExpectExec("DELETE FROM `group`")
+
+	if _, err := db.Query("DELETE FROM [post]").Exec(); err == nil {
+		t.Errorf("unexpected query: an error was expected but none given")
+	}
+	err := mock.ExpectationsWereMet()
+	if err == nil {
+		t.Fatal("an error was expected but none given")
+	}
+	for _, want := range []string{
+		"dalitest: got Exec \"DELETE FROM `post`\", want Exec \"DELETE FROM `user`\"",
+		"dalitest: expected Exec \"DELETE FROM `user`\" not executed",
+		"dalitest: expected Exec \"DELETE FROM `group`\" not executed",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("%q not contained in:\n%v", want, err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package dalitest
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mibk/dali"
+	"github.com/mibk/dali/dialect"
+	"github.com/mibk/dali/internal/testdriver"
+)
+
+// NewDryRun returns a DB in the dialect d that is not connected to
+// any database. It records all executed queries in the returned
+// Recording instead. Statements succeed without affecting any
+// rows and queries return no rows.
+func NewDryRun(d dialect.Dialect) (*dali.DB, *Recording) {
+	r := new(Recording)
+	return dali.NewDB(testdriver.Open(dryRunHandler{r}), d), r
+}
+
+// Recording holds queries executed by a dry-run DB.
+type Recording struct {
+	mu      sync.Mutex
+	queries []string
+}
+
+// Queries returns the recorded queries in the order in which they were
+// executed. Queries executed with args (i.e. prepared statements) are
+// followed by a comment listing the args. Transactions are recorded
+// as BEGIN, COMMIT, and ROLLBACK.
+func (r *Recording) Queries() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.queries...)
+}
+
+// Reset discards the recorded queries.
+func (r *Recording) Reset() {
+	r.mu.Lock()
+	r.queries = nil
+	r.mu.Unlock()
+}
+
+// String returns the recorded queries terminated by semicolons
+// and separated by blank lines.
+func (r *Recording) String() string {
+	var b strings.Builder
+	for i, q := range r.Queries() {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(q)
+		b.WriteString(";\n")
+	}
+	return b.String()
+}
+
+func (r *Recording) record(query string, args []driver.Value) {
+	if len(args) > 0 {
+		query = fmt.Sprintf("%s /* args: %v */", query, args)
+	}
+	r.mu.Lock()
+	r.queries = append(r.queries, query)
+	r.mu.Unlock()
+}
+
+// dryRunHandler serves the operations of the fake database
+// by recording them in r.
+type dryRunHandler struct {
+	r *Recording
+}
+
+func (h dryRunHandler) Begin() error {
+	h.r.record("BEGIN", nil)
+	return nil
+}
+
+func (h dryRunHandler) Commit() error {
+	h.r.record("COMMIT", nil)
+	return nil
+}
+
+func (h dryRunHandler) Rollback() error {
+	h.r.record("ROLLBACK", nil)
+	return nil
+}
+
+func (h dryRunHandler) Exec(query string, args []driver.Value) (driver.Result, error) {
+	h.r.record(query, args)
+	return driver.RowsAffected(0), nil
+}
+
+func (h dryRunHandler) Query(query string, args []driver.Value) (driver.Rows, error) {
+	h.r.record(query, args)
+	return testdriver.NewRows(nil, nil), nil
+}
@@ -0,0 +1,41 @@
+package dalitest
+
+import (
+	"testing"
+
+	"github.com/mibk/dali/dialect"
+)
+
+func TestDryRun(t *testing.T) {
+	db, rec := NewDryRun(dialect.MySQL)
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Query("INSERT INTO [user] ?values", user{Name: "Ada"}).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Query("UPDATE [user] ?set WHERE [id] = ?", user{Name: "Alan"}, 1).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	stmt, err := db.Prepare("SELECT * FROM [user] WHERE [id] = ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var users []user
+	if err := stmt.Bind(2).All(&users); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 0 {
+		t.Errorf("got %d users, want none", len(users))
+	}
+	Golden(t, "dryrun", rec.String())
+
+	rec.Reset()
+	if q := rec.Queries(); len(q) != 0 {
+		t.Errorf("got %q after Reset, want none", q)
+	}
+}
@@ -0,0 +1,79 @@
+package dalitest
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// updateEnv is the environment variable making Golden update
+// the golden files, as an alternative to the -update flag.
+const updateEnv = "DALITEST_UPDATE"
+
+func init() {
+	// Another package imported by the tests
+	// may have defined the flag already.
+	if flag.Lookup("update") == nil {
+		flag.Bool("update", false, "update the golden files of dalitest.Golden")
+	}
+}
+
+// updating reports whether the golden files should be updated.
+func updating() bool {
+	if os.Getenv(updateEnv) == "1" {
+		return true
+	}
+	f := flag.Lookup("update")
+	if f == nil {
+		return false
+	}
+	g, ok := f.Value.(flag.Getter)
+	if !ok {
+		return false
+	}
+	update, _ := g.Get().(bool)
+	return update
+}
+
+// Golden compares got with the content of the golden file
+// testdata/name.golden and reports a mismatch using t. If the
+// tests are run with the -update flag, or the DALITEST_UPDATE
+// environment variable is set to 1, the golden file is written
+// instead:
+//
+//	go test -update
+//
+// The flag is registered by dalitest unless it is already defined;
+// a test package defining its own -update flag should read it using
+// flag.Lookup instead. The environment variable is handy for
+// updating several packages, some of which don't use Golden:
+//
+//	DALITEST_UPDATE=1 go test ./...
+//
+// It is meant for reviewing SQL generated by dali:
+//
+//	db, rec := dalitest.NewDryRun(dialect.MySQL)
+//	// Run the tested code using db.
+//	dalitest.Golden(t, "create-user", rec.String())
+func Golden(t testing.TB, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if updating() {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+	if !bytes.Equal(want, []byte(got)) {
+		t.Errorf("%s mismatch (run with -update to update it):\n got:\n%s\nwant:\n%s", path, got, want)
+	}
+}
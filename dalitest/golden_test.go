@@ -0,0 +1,38 @@
+package dalitest
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGoldenUpdateFlag(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	update := flag.Lookup("update")
+	if update == nil {
+		t.Fatal("-update flag not registered")
+	}
+	old := update.Value.String()
+	defer update.Value.Set(old)
+
+	update.Value.Set("true")
+	Golden(t, "updated", "SELECT 1")
+	b, err := os.ReadFile(filepath.Join("testdata", "updated.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "SELECT 1" {
+		t.Errorf("got %q, want %q", b, "SELECT 1")
+	}
+
+	update.Value.Set("false")
+	Golden(t, "updated", "SELECT 1")
+}
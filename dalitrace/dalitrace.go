@@ -0,0 +1,166 @@
+// Package dalitrace provides tracing of dali queries, transactions,
+// and statement preparation using an abstract Tracer.
+//
+//	dalitrace.New(tracer).Install(db)
+//
+// Spans of queries executed within a transaction are children of
+// the span of the transaction, which lasts from DB.BeginTx until
+// Tx.Commit or Tx.Rollback, or until the context passed to BeginTx
+// is done (database/sql rolls the transaction back then). The oteltrace
+// module adapts an OpenTelemetry tracer.
+package dalitrace
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/mibk/dali"
+)
+
+// A Tracer starts spans.
+type Tracer interface {
+	// Start starts a span named name as a child of the span in ctx,
+	// if any. It returns a context carrying the new span.
+	Start(ctx context.Context, name string, attrs ...Attr) (context.Context, Span)
+}
+
+// A Span is a traced operation.
+type Span interface {
+	// End ends the span, recording err, if it is not nil.
+	End(err error)
+}
+
+// Attr is a span attribute.
+type Attr struct {
+	Key   string
+	Value interface{}
+}
+
+// The names of the spans.
+const (
+	SpanQuery   = "dali.Query"
+	SpanTx      = "dali.Tx"
+	SpanPrepare = "dali.Prepare"
+)
+
+// Tracing traces a DB using a Tracer.
+type Tracing struct {
+	tracer Tracer
+	txs    sync.Map // of *dali.Tx to *txSpan
+}
+
+type txSpan struct {
+	ctx  context.Context
+	span Span
+	stop func() bool // stops ending the span when BeginTx's ctx is done
+}
+
+// New returns new Tracing using t.
+func New(t Tracer) *Tracing {
+	return &Tracing{tracer: t}
+}
+
+// Install adds the tracing middleware and hook to db.
+func (t *Tracing) Install(db *dali.DB) {
+	db.Use(t.Middleware())
+	db.UseHooks(t.Hook)
+}
+
+// Middleware returns a dali.Middleware tracing queries. Spans of
+// queries returning rows end once the rows are returned, i.e. they don't
+// include the time spent reading them.
+func (t *Tracing) Middleware() dali.Middleware {
+	return func(e dali.Execer) dali.Execer {
+		return &execer{ex: e, t: t}
+	}
+}
+
+// Hook is a dali.Hook tracing transactions and statement preparation.
+func (t *Tracing) Hook(ctx context.Context, ev *dali.QueryEvent) func(error) {
+	switch ev.Op {
+	case dali.OpBegin:
+		tx := ev.Tx
+		sctx, span := t.tracer.Start(ctx, SpanTx)
+		s := &txSpan{ctx: sctx, span: span}
+		s.stop = context.AfterFunc(ctx, func() { t.endTx(tx, ctx.Err()) })
+		t.txs.Store(tx, s)
+		return func(err error) {
+			if err != nil {
+				t.endTx(ev.Tx, err)
+			}
+		}
+	case dali.OpCommit, dali.OpRollback:
+		return func(err error) { t.endTx(ev.Tx, err) }
+	case dali.OpPrepare:
+		_, span := t.tracer.Start(t.parent(ctx, ev), SpanPrepare, eventAttrs(ev)...)
+		return span.End
+	}
+	return nil
+}
+
+func (t *Tracing) endTx(tx *dali.Tx, err error) {
+	if s, ok := t.txs.LoadAndDelete(tx); ok {
+		s := s.(*txSpan)
+		s.stop()
+		s.span.End(err)
+	}
+}
+
+// parent returns the context carrying the parent span
+// of the operation described by ev.
+func (t *Tracing) parent(ctx context.Context, ev *dali.QueryEvent) context.Context {
+	if ev == nil || ev.Tx == nil {
+		return ctx
+	}
+	if s, ok := t.txs.Load(ev.Tx); ok {
+		return s.(*txSpan).ctx
+	}
+	return ctx
+}
+
+func eventAttrs(ev *dali.QueryEvent) []Attr {
+	attrs := []Attr{
+		{"db.statement", ev.Template},
+		{"dali.op", ev.Op.String()},
+	}
+	if ev.Prepared {
+		attrs = append(attrs, Attr{"dali.prepared", true})
+	}
+	return attrs
+}
+
+type execer struct {
+	ex dali.Execer
+	t  *Tracing
+}
+
+func (e *execer) start(ctx context.Context, query string, op dali.Op) Span {
+	ev, ok := dali.QueryEventFromContext(ctx)
+	if !ok {
+		ev = &dali.QueryEvent{Op: op, Template: query}
+	}
+	_, span := e.t.tracer.Start(e.t.parent(ctx, ev), SpanQuery, eventAttrs(ev)...)
+	return span
+}
+
+func (e *execer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	span := e.start(ctx, query, dali.OpExec)
+	res, err := e.ex.ExecContext(ctx, query, args...)
+	span.End(err)
+	return res, err
+}
+
+func (e *execer) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	span := e.start(ctx, query, dali.OpRows)
+	rows, err := e.ex.QueryContext(ctx, query, args...)
+	span.End(err)
+	return rows, err
+}
+
+func (e *execer) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	span := e.start(ctx, query, dali.OpScanRow)
+	row := e.ex.QueryRowContext(ctx, query, args...)
+	span.End(row.Err())
+	return row
+}
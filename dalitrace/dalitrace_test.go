@@ -0,0 +1,129 @@
+package dalitrace
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mibk/dali"
+	"github.com/mibk/dali/dialect"
+	"github.com/mibk/dali/internal/testdriver"
+)
+
+func TestTracing(t *testing.T) {
+	_, handle := testdriver.New()
+	db := dali.NewDB(handle, dialect.MySQL)
+	rec := new(Recorder)
+	New(rec).Install(db)
+
+	db.Query("SELECT ?", 1).Exec()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Query("UPDATE [user] SET [name] = ?", "Eve").Exec()
+	stmt, err := tx.Prepare("SELECT [name] FROM [user] WHERE [id] = ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt.Bind(4).Rows()
+	tx.Commit()
+	tx.Rollback()
+
+	spans := rec.Spans()
+	want := []struct {
+		name     string
+		template string
+		parent   int // index in spans, or -1
+	}{
+		{SpanQuery, "SELECT ?", -1},
+		{SpanTx, "", -1},
+		{SpanQuery, "UPDATE [user] SET [name] = ?", 1},
+		{SpanPrepare, "SELECT [name] FROM [user] WHERE [id] = ?", 1},
+		{SpanQuery, "SELECT [name] FROM [user] WHERE [id] = ?", 1},
+	}
+	if len(spans) != len(want) {
+		t.Fatalf("got %d spans, want %d", len(spans), len(want))
+	}
+	for i, w := range want {
+		s := spans[i]
+		if s.Name != w.name {
+			t.Errorf("#%d: got name %s, want %s", i, s.Name, w.name)
+		}
+		if w.template != "" && s.Attr("db.statement") != w.template {
+			t.Errorf("#%d: got statement %v, want %s", i, s.Attr("db.statement"), w.template)
+		}
+		var parent *RecordedSpan
+		if w.parent >= 0 {
+			parent = spans[w.parent]
+		}
+		if s.Parent != parent {
+			t.Errorf("#%d: unexpected parent %v", i, s.Parent)
+		}
+		if ended, err := s.Ended(); !ended || err != nil {
+			t.Errorf("#%d: ended: %v, err: %v", i, ended, err)
+		}
+	}
+	if got := spans[4].Attr("dali.op"); got != "Rows" {
+		t.Errorf("got op %v, want Rows", got)
+	}
+}
+
+func TestTracingError(t *testing.T) {
+	drv, handle := testdriver.New()
+	db := dali.NewDB(handle, dialect.MySQL)
+	rec := new(Recorder)
+	New(rec).Install(db)
+
+	errTimeout := errors.New("timeout")
+	drv.SetErr(errTimeout)
+	db.Query("SELECT 1").Rows()
+	spans := rec.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if _, err := spans[0].Ended(); err != errTimeout {
+		t.Errorf("got err %v, want %v", err, errTimeout)
+	}
+}
+
+func TestTracingTxCanceled(t *testing.T) {
+	_, handle := testdriver.New()
+	db := dali.NewDB(handle, dialect.MySQL)
+	rec := new(Recorder)
+	tr := New(rec)
+	tr.Install(db)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	span := rec.Spans()[0]
+	deadline := time.Now().Add(time.Second)
+	for {
+		if ended, err := span.Ended(); ended {
+			if err != context.Canceled {
+				t.Errorf("got err %v, want %v", err, context.Canceled)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("span of the canceled transaction not ended")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	tr.txs.Range(func(key, _ interface{}) bool {
+		t.Errorf("span of %v not released", key)
+		return true
+	})
+
+	// A rollback after the cancellation doesn't end the span again.
+	tx.Rollback()
+	if _, err := span.Ended(); err != context.Canceled {
+		t.Errorf("got err %v, want %v", err, context.Canceled)
+	}
+}
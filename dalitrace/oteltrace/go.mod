@@ -0,0 +1,9 @@
+module github.com/mibk/dali/dalitrace/oteltrace
+
+go 1.22.0
+
+require (
+	github.com/mibk/dali v0.0.0-20261018223919-913cd952eeeb
+	go.opentelemetry.io/otel v1.24.0
+	go.opentelemetry.io/otel/trace v1.24.0
+)
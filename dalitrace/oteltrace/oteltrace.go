@@ -0,0 +1,64 @@
+// Package oteltrace adapts an OpenTelemetry tracer to dalitrace.Tracer.
+//
+//	dalitrace.New(oteltrace.New(otel.Tracer("dali"))).Install(db)
+package oteltrace
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/mibk/dali/dalitrace"
+)
+
+// New returns a dalitrace.Tracer starting spans using t.
+func New(t trace.Tracer) dalitrace.Tracer {
+	return tracer{t}
+}
+
+type tracer struct {
+	t trace.Tracer
+}
+
+func (t tracer) Start(ctx context.Context, name string, attrs ...dalitrace.Attr) (context.Context, dalitrace.Span) {
+	kvs := make([]attribute.KeyValue, len(attrs))
+	for i, a := range attrs {
+		kvs[i] = keyValue(a)
+	}
+	ctx, s := t.t.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(kvs...))
+	return ctx, span{s}
+}
+
+func keyValue(a dalitrace.Attr) attribute.KeyValue {
+	switch v := a.Value.(type) {
+	case string:
+		return attribute.String(a.Key, v)
+	case bool:
+		return attribute.Bool(a.Key, v)
+	case int:
+		return attribute.Int(a.Key, v)
+	case int64:
+		return attribute.Int64(a.Key, v)
+	case float64:
+		return attribute.Float64(a.Key, v)
+	default:
+		return attribute.String(a.Key, fmt.Sprint(v))
+	}
+}
+
+type span struct {
+	s trace.Span
+}
+
+func (s span) End(err error) {
+	if err != nil {
+		s.s.RecordError(err)
+		s.s.SetStatus(codes.Error, err.Error())
+	}
+	s.s.End()
+}
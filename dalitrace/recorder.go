@@ -0,0 +1,68 @@
+package dalitrace
+
+import (
+	"context"
+	"sync"
+)
+
+// Recorder is a Tracer keeping all spans in memory.
+// It is meant for tests.
+type Recorder struct {
+	mu    sync.Mutex
+	spans []*RecordedSpan
+}
+
+// RecordedSpan is a span started by Recorder.
+type RecordedSpan struct {
+	Name   string
+	Attrs  []Attr
+	Parent *RecordedSpan // or nil
+
+	mu    sync.Mutex
+	ended bool
+	err   error
+}
+
+type spanKey struct{}
+
+// Start implements Tracer.
+func (r *Recorder) Start(ctx context.Context, name string, attrs ...Attr) (context.Context, Span) {
+	s := &RecordedSpan{Name: name, Attrs: attrs}
+	s.Parent, _ = ctx.Value(spanKey{}).(*RecordedSpan)
+	r.mu.Lock()
+	r.spans = append(r.spans, s)
+	r.mu.Unlock()
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// Spans returns all spans in the order in which they were started.
+func (r *Recorder) Spans() []*RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*RecordedSpan(nil), r.spans...)
+}
+
+// End implements Span.
+func (s *RecordedSpan) End(err error) {
+	s.mu.Lock()
+	s.ended, s.err = true, err
+	s.mu.Unlock()
+}
+
+// Ended reports whether the span has ended, and if so,
+// with what error.
+func (s *RecordedSpan) Ended() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ended, s.err
+}
+
+// Attr returns the value of the attribute key, or nil.
+func (s *RecordedSpan) Attr(key string) interface{} {
+	for _, a := range s.Attrs {
+		if a.Key == key {
+			return a.Value
+		}
+	}
+	return nil
+}
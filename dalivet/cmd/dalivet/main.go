@@ -0,0 +1,13 @@
+// Command dalivet checks dali query templates. It can be run
+// either directly, or by go vet:
+//
+//	go vet -vettool=$(which dalivet) ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/mibk/dali/dalivet"
+)
+
+func main() { singlechecker.Main(dalivet.Analyzer) }
@@ -0,0 +1,220 @@
+// Package dalivet defines an Analyzer checking dali query templates.
+//
+// The analyzer finds calls of the Query, QueryWithContext, Prepare, and
+// PrepareContext methods of dali.DB, dali.Tx, and dali.Querier with
+// constant templates and reports errors that would otherwise be reported
+// at run time: unterminated identifiers, unknown placeholders, placeholders
+// not allowed in prepared statements, mismatched numbers of placeholders
+// and args, and args of types not accepted by their placeholders.
+package dalivet
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+
+	"github.com/mibk/dali/internal/placeholder"
+)
+
+// Analyzer checks dali query templates.
+var Analyzer = &analysis.Analyzer{
+	Name:     "dalivet",
+	Doc:      "check placeholders and args of dali queries",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+const daliPath = "github.com/mibk/dali"
+
+// methods maps the checked methods to the index of the query param.
+var methods = map[string]int{
+	"Query":            0,
+	"QueryWithContext": 1,
+	"Prepare":          0,
+	"PrepareContext":   1,
+}
+
+var receivers = map[string]bool{"DB": true, "Tx": true, "Querier": true}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	insp.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+		if !ok || fn.Pkg() == nil || fn.Pkg().Path() != daliPath {
+			return
+		}
+		queryIndex, ok := methods[fn.Name()]
+		if !ok || !isDaliMethod(fn) || len(call.Args) <= queryIndex {
+			return
+		}
+		tv := pass.TypesInfo.Types[call.Args[queryIndex]]
+		if tv.Value == nil || tv.Value.Kind() != constant.String {
+			return
+		}
+		c := checker{
+			pass:     pass,
+			dali:     fn.Pkg(),
+			call:     call,
+			query:    call.Args[queryIndex],
+			args:     call.Args[queryIndex+1:],
+			variadic: call.Ellipsis.IsValid(),
+			prepared: fn.Name() == "Prepare" || fn.Name() == "PrepareContext",
+		}
+		c.check(constant.StringVal(tv.Value))
+	})
+	return nil, nil
+}
+
+func isDaliMethod(fn *types.Func) bool {
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return false
+	}
+	t := recv.Type()
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	named, ok := t.(*types.Named)
+	return ok && receivers[named.Obj().Name()]
+}
+
+type checker struct {
+	pass     *analysis.Pass
+	dali     *types.Package
+	call     *ast.CallExpr
+	query    ast.Expr
+	args     []ast.Expr
+	variadic bool
+	prepared bool
+}
+
+// notPrepared are the placeholders not allowed in prepared statements.
+var notPrepared = map[string]bool{
+	"?...":       true,
+	"?values":    true,
+	"?values...": true,
+	"?set":       true,
+}
+
+func (c *checker) check(query string) {
+	phs, err := placeholder.Parse(query)
+	if err != nil {
+		c.pass.Reportf(c.query.Pos(), "dali: %v", err)
+		return
+	}
+
+	// Placeholders consuming args.
+	var consuming []placeholder.Placeholder
+	for _, ph := range phs {
+		if c.prepared {
+			if notPrepared[ph.Name] {
+				c.pass.Reportf(c.query.Pos(), "dali: %s cannot be used in prepared statements", ph.Name)
+				return
+			}
+			if ph.Name == "?" {
+				// Bound later.
+				continue
+			}
+		}
+		consuming = append(consuming, ph)
+	}
+	if c.variadic {
+		// The args are not known.
+		return
+	}
+
+	switch {
+	case len(c.args) < len(consuming):
+		ph := consuming[len(c.args)]
+		c.pass.Reportf(c.call.Rparen, "dali: there is not enough args for placeholders: missing arg for %s at offset %d",
+			ph.Name, ph.Offset)
+	case len(c.args) > len(consuming):
+		c.pass.Reportf(c.args[len(consuming)].Pos(), "dali: only %d args are expected", len(consuming))
+	}
+	for i, ph := range consuming {
+		if i >= len(c.args) {
+			break
+		}
+		c.checkArg(ph, c.args[i])
+	}
+}
+
+func (c *checker) checkArg(ph placeholder.Placeholder, arg ast.Expr) {
+	tv, ok := c.pass.TypesInfo.Types[arg]
+	if !ok || tv.Type == nil {
+		return
+	}
+	typ := tv.Type
+	if types.IsInterface(typ) {
+		// The dynamic type is not known.
+		return
+	}
+	var msg string
+	switch ph.Name {
+	case "?ident":
+		if !isString(typ) {
+			msg = "?ident expects the argument to be a string"
+		}
+	case "?ident...":
+		if s, ok := typ.Underlying().(*types.Slice); !ok || !isString(s.Elem()) {
+			msg = "?ident... expects the argument to be a []string"
+		}
+	case "?...":
+		if _, ok := typ.Underlying().(*types.Slice); !ok {
+			msg = "?... expects the argument to be a slice"
+		}
+	case "?values", "?set":
+		if !isMap(typ) && !isStruct(typ, true) {
+			msg = ph.Name + " expects the argument to be a dali.Map, or a struct or a pointer to it"
+		}
+	case "?values...":
+		if s, ok := typ.Underlying().(*types.Slice); !ok || !isStruct(s.Elem(), true) {
+			msg = "?values... expects the argument to be a slice of structs"
+		}
+	case "?sql":
+		if !isString(typ) && !c.isMarshaler(typ) {
+			msg = "?sql expects the argument to be a string or Marshaler"
+		}
+	}
+	if msg != "" {
+		c.pass.Reportf(arg.Pos(), "dali: %s (at offset %d); got %s", msg, ph.Offset, typ)
+	}
+}
+
+// isString reports whether t is string, which is the only type
+// the translator accepts for identifiers (not even types based
+// on string are accepted).
+func isString(t types.Type) bool {
+	b, ok := t.(*types.Basic)
+	return ok && (b.Kind() == types.String || b.Kind() == types.UntypedString)
+}
+
+func isMap(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Pkg() != nil &&
+		named.Obj().Pkg().Path() == daliPath && named.Obj().Name() == "Map"
+}
+
+func isStruct(t types.Type, allowPtr bool) bool {
+	if p, ok := t.Underlying().(*types.Pointer); ok && allowPtr {
+		t = p.Elem()
+	}
+	_, ok := t.Underlying().(*types.Struct)
+	return ok
+}
+
+// isMarshaler reports whether t implements dali.Marshaler.
+func (c *checker) isMarshaler(t types.Type) bool {
+	obj, ok := c.dali.Scope().Lookup("Marshaler").(*types.TypeName)
+	if !ok {
+		return false
+	}
+	iface, ok := obj.Type().Underlying().(*types.Interface)
+	return ok && types.Implements(t, iface)
+}
@@ -0,0 +1,59 @@
+package a
+
+import (
+	"context"
+
+	"github.com/mibk/dali"
+)
+
+type user struct {
+	ID   int64
+	Name string
+}
+
+type name string
+
+type where struct{}
+
+func (where) MarshalSQL(t dali.Translator) (string, error) { return "", nil }
+
+type ptrWhere struct{}
+
+func (*ptrWhere) MarshalSQL(t dali.Translator) (string, error) { return "", nil }
+
+type badWhere struct{}
+
+func (badWhere) MarshalSQL() string { return "" }
+
+func queries(db *dali.DB, tx *dali.Tx, q dali.Querier, args []interface{}, v interface{}) {
+	ctx := context.Background()
+
+	db.Query("SELECT [id FROM user")            // want `\[ at offset 7: identifier not terminated`
+	db.Query("SELECT * FROM ?table")            // want `unknown placeholder \?table`
+	db.Query("SELECT ?, ?", 1)                  // want `not enough args for placeholders: missing arg for \? at offset 10`
+	tx.Query("SELECT ?", 1, 2)                  // want `only 1 args are expected`
+	q.QueryWithContext(ctx, "SELECT ?", 1)      // OK
+	q.Query("SELECT ?, ?", args...)             // OK: args not known
+	db.Query("SELECT ?ident FROM t", name("a")) // want `\?ident expects the argument to be a string \(at offset 7\); got a.name`
+	db.Query("SELECT ?ident FROM t", "id")      // OK
+	db.Query("SELECT ?ident FROM t", v)         // OK: dynamic type not known
+	db.Query("SELECT ?ident...", []string{"a"}) // OK
+	db.Query("SELECT ?ident...", []int{1})      // want `\?ident... expects the argument to be a \[\]string`
+	db.Query("WHERE id IN (?...)", 14)          // want `\?... expects the argument to be a slice`
+	db.Query("INSERT ?values", user{})          // OK
+	db.Query("INSERT ?values", &user{})         // OK
+	db.Query("UPDATE ?set", dali.Map{})         // OK
+	db.Query("UPDATE ?set", 5)                  // want `\?set expects the argument to be a dali.Map, or a struct or a pointer to it`
+	db.Query("INSERT ?values...", []*user{})    // OK
+	db.Query("INSERT ?values...", []int{})      // want `\?values... expects the argument to be a slice of structs`
+	db.Query("WHERE ?sql", where{})             // OK
+	db.Query("WHERE ?sql", 5)                   // want `\?sql expects the argument to be a string or Marshaler`
+	db.Query("WHERE ?sql", &ptrWhere{})         // OK
+	db.Query("WHERE ?sql", ptrWhere{})          // want `\?sql expects the argument to be a string or Marshaler`
+	db.Query("WHERE ?sql", badWhere{})          // want `\?sql expects the argument to be a string or Marshaler`
+
+	db.Prepare("SELECT ?ident WHERE id = ?", "name")       // OK
+	db.PrepareContext(ctx, "SELECT ?ident WHERE id = ?")   // want `not enough args`
+	db.Prepare("INSERT ?values", user{})                   // want `\?values cannot be used in prepared statements`
+	db.Prepare("SELECT * WHERE id IN (?...)", []int{1, 2}) // want `\?... cannot be used in prepared statements`
+}
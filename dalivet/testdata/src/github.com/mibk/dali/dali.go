@@ -0,0 +1,33 @@
+// Package dali is a stub of the real package for testing the analyzer.
+package dali
+
+import "context"
+
+type DB struct{}
+type Tx struct{}
+type Query struct{}
+type Stmt struct{}
+type Map map[string]interface{}
+type Translator struct{}
+
+type Marshaler interface {
+	MarshalSQL(t Translator) (string, error)
+}
+
+type Querier interface {
+	Query(query string, args ...interface{}) *Query
+	QueryWithContext(ctx context.Context, query string, args ...interface{}) *Query
+	Prepare(query string, args ...interface{}) (*Stmt, error)
+	PrepareContext(ctx context.Context, query string, args ...interface{}) (*Stmt, error)
+}
+
+func (db *DB) Query(query string, args ...interface{}) *Query { return nil }
+func (db *DB) QueryWithContext(ctx context.Context, query string, args ...interface{}) *Query {
+	return nil
+}
+func (db *DB) Prepare(query string, args ...interface{}) (*Stmt, error) { return nil, nil }
+func (db *DB) PrepareContext(ctx context.Context, query string, args ...interface{}) (*Stmt, error) {
+	return nil, nil
+}
+
+func (tx *Tx) Query(query string, args ...interface{}) *Query { return nil }
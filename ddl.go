@@ -0,0 +1,203 @@
+package dali
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/mibk/dali/dialect"
+)
+
+// TableDDL returns the statements creating the table for rows
+// represented by the struct v, or a pointer to it, in the dialect d.
+// The first statement is CREATE TABLE, it is followed by a CREATE INDEX
+// statement for each index.
+//
+// The columns are derived the same way as for ?values, but selectonly
+// fields are included. Their types are mapped by the dialect, which must
+// implement dialect.TypeMapper, unless specified by the following db tag
+// options:
+//
+//	type=T    the column type, e.g. type=DECIMAL(10,2)
+//	size=N    the maximum length of strings and byte slices (a positive
+//	          integer)
+//	null      the column is nullable; pointers and sql.Null* types
+//	          are nullable implicitly
+//	pk        the column is (a part of) the primary key; an integer
+//	          selectonly primary key is auto-incremented as defined
+//	          by the dialect
+//	index     the column is indexed; columns with index=NAME
+//	          form a composite index NAME
+//	unique    same as index, but the index is unique
+//
+// For example:
+//
+//	type User struct {
+//		ID      int64     `db:"id,selectonly,pk"`
+//		Email   string    `db:"email,size=100,unique"`
+//		GroupID int64     `db:"group_id,index=group_created"`
+//		Created time.Time `db:"created,index=group_created"`
+//		Note    *string   `db:"note,type=TEXT"`
+//	}
+//
+// Unnamed indexes are named after the table and the column,
+// e.g. user_email.
+func TableDDL(d dialect.Dialect, table string, v interface{}) ([]string, error) {
+	typ := reflect.TypeOf(v)
+	if typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, errors.New("dali: TableDDL expects a struct or a pointer to a struct")
+	}
+	tm, _ := d.(dialect.TypeMapper)
+
+	type tableIndex struct {
+		name   string
+		unique bool
+		cols   []string
+	}
+	var (
+		b        strings.Builder
+		pk       []string
+		inlinePK string // auto-incremented column declaring the primary key
+		indexes  []*tableIndex
+		byName   = make(map[string]*tableIndex)
+		colNames = make(map[string]bool)
+	)
+	b.WriteString("CREATE TABLE ")
+	d.EscapeIdent(&b, table)
+	b.WriteString(" (")
+	cols, fieldIndexes := colNamesAndFieldIndexes(typ, false)
+	for i, col := range cols {
+		f := typ.FieldByIndex(fieldIndexes[i])
+		if colNames[col] {
+			return nil, fmt.Errorf("dali: duplicate column %s", col)
+		}
+		colNames[col] = true
+		prop, err := parseFieldProp(f.Tag.Get("db"))
+		if err != nil {
+			return nil, fmt.Errorf("dali: field %s: %v", f.Name, err)
+		}
+		kind, nullable, ok := columnKind(f.Type)
+		colType := prop.Type
+		if colType == "" {
+			if !ok {
+				return nil, fmt.Errorf("dali: cannot map %s of field %s to a column type, use the type option", f.Type, f.Name)
+			}
+			if tm == nil {
+				return nil, fmt.Errorf("dali: dialect %T cannot map column types, use the type option", d)
+			}
+			colType = tm.ColumnType(kind, prop.Size)
+		}
+
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString("\n\t")
+		d.EscapeIdent(&b, col)
+		switch {
+		case prop.PK && prop.SelectOnly && ok && tm != nil &&
+			(kind == dialect.IntColumn || kind == dialect.UintColumn):
+			def, inline := tm.AutoIncrement(colType)
+			b.WriteString(" " + def)
+			if inline {
+				inlinePK = col
+			} else {
+				pk = append(pk, col)
+			}
+		case prop.PK:
+			b.WriteString(" " + colType + " NOT NULL")
+			pk = append(pk, col)
+		case !nullable && !prop.Null:
+			b.WriteString(" " + colType + " NOT NULL")
+		default:
+			b.WriteString(" " + colType)
+		}
+		for _, ix := range prop.Indexes {
+			name := ix.Name
+			if name == "" {
+				name = table + "_" + col
+			}
+			ti := byName[name]
+			if ti == nil {
+				ti = &tableIndex{name: name}
+				byName[name] = ti
+				indexes = append(indexes, ti)
+			}
+			ti.unique = ti.unique || ix.Unique
+			ti.cols = append(ti.cols, col)
+		}
+	}
+	if inlinePK != "" && len(pk) > 0 {
+		return nil, fmt.Errorf("dali: dialect %T cannot auto-increment column %s of a composite primary key", d, inlinePK)
+	}
+	if len(pk) > 0 {
+		b.WriteString(",\n\tPRIMARY KEY (")
+		writeIdents(&b, d, pk)
+		b.WriteByte(')')
+	}
+	b.WriteString("\n)")
+
+	stmts := []string{b.String()}
+	for _, ti := range indexes {
+		b.Reset()
+		b.WriteString("CREATE ")
+		if ti.unique {
+			b.WriteString("UNIQUE ")
+		}
+		b.WriteString("INDEX ")
+		d.EscapeIdent(&b, ti.name)
+		b.WriteString(" ON ")
+		d.EscapeIdent(&b, table)
+		b.WriteString(" (")
+		writeIdents(&b, d, ti.cols)
+		b.WriteByte(')')
+		stmts = append(stmts, b.String())
+	}
+	return stmts, nil
+}
+
+func writeIdents(b *strings.Builder, d dialect.Dialect, idents []string) {
+	for i, id := range idents {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		d.EscapeIdent(b, id)
+	}
+}
+
+// columnKind returns the kind of a column holding values of t
+// and whether the column is implicitly nullable. ok is false
+// if the kind cannot be determined.
+func columnKind(t reflect.Type) (k dialect.ColumnKind, nullable, ok bool) {
+	if t.Kind() == reflect.Ptr {
+		t, nullable = t.Elem(), true
+	}
+	if t.Kind() == reflect.Struct && t.PkgPath() == "database/sql" &&
+		strings.HasPrefix(t.Name(), "Null") && t.NumField() == 2 {
+		// sql.NullString, sql.Null[T], etc.
+		t, nullable = t.Field(0).Type, true
+	}
+	if t == timeType {
+		return dialect.TimeColumn, nullable, true
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return dialect.BoolColumn, nullable, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return dialect.IntColumn, nullable, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return dialect.UintColumn, nullable, true
+	case reflect.Float32, reflect.Float64:
+		return dialect.FloatColumn, nullable, true
+	case reflect.String:
+		return dialect.StringColumn, nullable, true
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return dialect.BytesColumn, nullable, true
+		}
+	}
+	return 0, false, false
+}
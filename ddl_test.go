@@ -0,0 +1,151 @@
+package dali
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mibk/dali/dialect"
+)
+
+type ddlUser struct {
+	ID      int64     `db:"id,selectonly,pk"`
+	Email   string    `db:"email,size=100,unique"`
+	GroupID uint32    `db:"group_id,index=group_created"`
+	Created time.Time `db:"created,index=group_created"`
+	Note    *string   `db:"note,type=TEXT"`
+	Score   float64   `db:"score,null,index"`
+	Price   string    `db:"price,type=DECIMAL(10,2)"`
+	Admin   sql.NullBool
+	Avatar  []byte `db:"avatar,size=16"`
+	ddlBase
+	Ignored string `db:"-"`
+}
+
+type ddlBase struct {
+	Version int `db:"version"`
+}
+
+func TestTableDDL(t *testing.T) {
+	got, err := TableDDL(dialect.MySQL, "user", &ddlUser{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"CREATE TABLE `user` (\n" +
+			"\t`id` BIGINT NOT NULL AUTO_INCREMENT,\n" +
+			"\t`email` VARCHAR(100) NOT NULL,\n" +
+			"\t`group_id` BIGINT UNSIGNED NOT NULL,\n" +
+			"\t`created` DATETIME(6) NOT NULL,\n" +
+			"\t`note` TEXT,\n" +
+			"\t`score` DOUBLE,\n" +
+			"\t`price` DECIMAL(10,2) NOT NULL,\n" +
+			"\t`Admin` BOOLEAN,\n" +
+			"\t`avatar` VARBINARY(16) NOT NULL,\n" +
+			"\t`version` BIGINT NOT NULL,\n" +
+			"\tPRIMARY KEY (`id`)\n" +
+			")",
+		"CREATE UNIQUE INDEX `user_email` ON `user` (`email`)",
+		"CREATE INDEX `group_created` ON `user` (`group_id`, `created`)",
+		"CREATE INDEX `user_score` ON `user` (`score`)",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, ";\n"), strings.Join(want, ";\n"))
+	}
+
+	// Composite primary key without auto-increment.
+	type membership struct {
+		UserID  int64 `db:"user_id,pk"`
+		GroupID int64 `db:"group_id,pk"`
+	}
+	got, err = TableDDL(dialect.MySQL, "membership", membership{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "CREATE TABLE `membership` (\n\t`user_id` BIGINT NOT NULL,\n\t`group_id` BIGINT NOT NULL,\n" +
+		"\tPRIMARY KEY (`user_id`, `group_id`)\n)"; len(got) != 1 || got[0] != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// sqlite maps column types like SQLite, which declares auto-incremented
+// primary keys inline.
+type sqlite struct{ dialect.Dialect }
+
+func (sqlite) ColumnType(k dialect.ColumnKind, size int) string {
+	switch k {
+	case dialect.BoolColumn, dialect.IntColumn, dialect.UintColumn:
+		return "INTEGER"
+	case dialect.FloatColumn:
+		return "REAL"
+	case dialect.BytesColumn:
+		return "BLOB"
+	}
+	return "TEXT"
+}
+
+func (sqlite) AutoIncrement(typ string) (def string, inlinePK bool) {
+	return "INTEGER PRIMARY KEY AUTOINCREMENT", true
+}
+
+func TestTableDDLInlinePK(t *testing.T) {
+	type user struct {
+		ID   int64  `db:"id,selectonly,pk"`
+		Name string `db:"name,size=100"`
+	}
+	got, err := TableDDL(sqlite{dialect.MySQL}, "user", user{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "CREATE TABLE `user` (\n\t`id` INTEGER PRIMARY KEY AUTOINCREMENT,\n\t`name` TEXT NOT NULL\n)"
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestTableDDLErrors(t *testing.T) {
+	tests := []struct {
+		d   dialect.Dialect
+		v   interface{}
+		err string
+	}{
+		{dialect.MySQL, 5, "dali: TableDDL expects a struct or a pointer to a struct"},
+		{dialect.MySQL, nil, "dali: TableDDL expects a struct or a pointer to a struct"},
+		{dialect.MySQL, struct{ Tags []string }{},
+			"dali: cannot map []string of field Tags to a column type, use the type option"},
+		{dialect.MySQL, struct {
+			A int `db:"a"`
+			B int `db:"a"`
+		}{}, "dali: duplicate column a"},
+		{dvr, struct{ A int }{}, "dali: dialect *dali.FakeDialect cannot map column types, use the type option"},
+		{dialect.MySQL, struct {
+			Name string `db:"name,size=abc"`
+		}{}, "dali: field Name: invalid size=abc"},
+		{dialect.MySQL, struct {
+			Name string `db:"name,size=-1"`
+		}{}, "dali: field Name: invalid size=-1"},
+		{sqlite{dialect.MySQL}, struct {
+			ID      int64 `db:"id,selectonly,pk"`
+			GroupID int64 `db:"group_id,pk"`
+		}{}, "dali: dialect dali.sqlite cannot auto-increment column id of a composite primary key"},
+	}
+	for _, tt := range tests {
+		_, err := TableDDL(tt.d, "t", tt.v)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("%T: got %v, want %s", tt.v, err, tt.err)
+		}
+	}
+
+	// Explicit types don't need a TypeMapper.
+	got, err := TableDDL(dvr, "t", struct {
+		A int `db:"a,type=INTEGER,pk"`
+	}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "CREATE TABLE {t} (\n\t{a} INTEGER NOT NULL,\n\tPRIMARY KEY ({a})\n)"; got[0] != want {
+		t.Errorf("got %q, want %q", got[0], want)
+	}
+}
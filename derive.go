@@ -3,7 +3,9 @@ package dali
 import (
 	"database/sql"
 	"database/sql/driver"
+	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -39,7 +41,7 @@ func colNamesAndFieldIndexesBase(baseIndex []int, typ reflect.Type, insert bool)
 			continue
 		}
 
-		prop := parseFieldProp(f.Tag.Get("db"))
+		prop, _ := parseFieldProp(f.Tag.Get("db")) // DDL options are checked by TableDDL
 		if prop.Ignore || insert && prop.SelectOnly {
 			continue
 		}
@@ -61,19 +63,77 @@ type fieldProps struct {
 	ColName    string
 	SelectOnly bool
 	Ignore     bool
+
+	// Used for generating DDL.
+	Type    string
+	Size    int
+	Null    bool
+	PK      bool
+	Indexes []index
+}
+
+// index is an index a column is a part of.
+type index struct {
+	Name   string // empty for a single column index
+	Unique bool
 }
 
-func parseFieldProp(s string) fieldProps {
-	props := strings.Split(s, ",")
+// parseFieldProp parses the db tag s. It reports malformed options
+// used for generating DDL, but the returned props are valid anyway.
+func parseFieldProp(s string) (fieldProps, error) {
+	props := splitTag(s)
 	if props[0] == "-" {
-		return fieldProps{Ignore: true}
+		return fieldProps{Ignore: true}, nil
 	}
+	var err error
 	p := fieldProps{ColName: props[0]}
 	for _, prop := range props[1:] {
-		switch prop {
+		key, val, _ := strings.Cut(prop, "=")
+		switch key {
 		case "selectonly":
 			p.SelectOnly = true
+		case "type":
+			p.Type = val
+		case "size":
+			n, serr := strconv.Atoi(val)
+			if serr != nil || n <= 0 {
+				err = fmt.Errorf("invalid size=%s", val)
+				continue
+			}
+			p.Size = n
+		case "null":
+			p.Null = true
+		case "pk":
+			p.PK = true
+		case "index":
+			p.Indexes = append(p.Indexes, index{Name: val})
+		case "unique":
+			p.Indexes = append(p.Indexes, index{Name: val, Unique: true})
+		}
+	}
+	return p, err
+}
+
+// splitTag splits a tag value by commas except for those
+// in parentheses, such as in type=DECIMAL(10,2).
+func splitTag(s string) []string {
+	var (
+		props []string
+		depth int
+		start int
+	)
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				props = append(props, s[start:i])
+				start = i + 1
+			}
 		}
 	}
-	return p
+	return append(props, s[start:])
 }
@@ -1,3 +1,12 @@
+// Package dialect defines the dialects of SQL drivers used by dali.
+//
+// Besides escaping, dialects may implement optional interfaces,
+// such as SchemaQuerier or AdvisoryLocker, supplying SQL statements.
+// All such statements are dali query templates: identifiers are
+// quoted using [brackets] and args are passed using the dali
+// placeholders (?, ?..., etc.), never the driver's own placeholders.
+// The templates are translated using the dialect before they are
+// executed.
 package dialect
 
 import (
@@ -27,6 +36,127 @@ type Dialect interface {
 	PrintPlaceholderSign(w io.Writer, n int)
 }
 
+// ReplicationLagQuerier is an optional interface implemented by dialects
+// that can report the replication lag of a replica.
+type ReplicationLagQuerier interface {
+	// ReplicationLagQuery returns the statement reporting the replication
+	// status and the name of the column holding the lag in seconds.
+	ReplicationLagQuery() (query, column string)
+}
+
+// SchemaQuerier is an optional interface implemented by dialects
+// that can describe the schema of the current database.
+type SchemaQuerier interface {
+	// TablesQuery returns the query listing the tables (and views)
+	// ordered by name. The query has no args and returns these columns:
+	//
+	//	name    table name
+	//	view    whether the table is a view
+	TablesQuery() string
+
+	// ColumnsQuery returns the query listing the columns of tables
+	// ordered by the table name and the column position. The query
+	// takes the table names as the only arg (a []string to be used
+	// with ?...) and returns these columns:
+	//
+	//	table          table name
+	//	name           column name
+	//	data_type      type without modifiers, e.g. "int"
+	//	column_type    full type, e.g. "int(10) unsigned"
+	//	nullable       whether the column can be NULL
+	//	primary_key    whether the column is a part of the primary key
+	//	auto_increment whether the column is auto-incremented
+	ColumnsQuery() string
+
+	// IndexesQuery returns the query listing the indexes of a table,
+	// including the primary key, one row for each indexed column,
+	// ordered by the index name and the column position in the index.
+	// The query takes the table name as the only arg and returns these
+	// columns:
+	//
+	//	name    index name
+	//	unique  whether the index is unique
+	//	column  column name
+	IndexesQuery() string
+}
+
+// AdvisoryLocker is an optional interface implemented by dialects
+// supporting named advisory locks.
+type AdvisoryLocker interface {
+	// AdvisoryLockQueries returns the queries acquiring and releasing
+	// a named lock. Both take the name as the only arg. The lock query
+	// waits until the lock is acquired and returns a single value,
+	// which is true if it succeeded. The lock is held by the connection.
+	AdvisoryLockQueries() (lock, unlock string)
+}
+
+// DDLTransactor is an optional interface implemented by dialects
+// that can report whether DDL statements, such as CREATE TABLE,
+// can be executed in transactions and rolled back.
+type DDLTransactor interface {
+	TransactionalDDL() bool
+}
+
+// StatementSplitter is an optional interface implemented by dialects
+// that can split SQL scripts into separate statements.
+type StatementSplitter interface {
+	// SplitStatements returns the statements of script
+	// without the terminating semicolons.
+	SplitStatements(script string) []string
+}
+
+// ColumnKind is a dialect independent kind of values stored in a column.
+type ColumnKind int
+
+// The column kinds mapped by TypeMapper.
+const (
+	BoolColumn ColumnKind = iota
+	IntColumn
+	UintColumn
+	FloatColumn
+	StringColumn
+	BytesColumn
+	TimeColumn
+)
+
+// TypeMapper is an optional interface implemented by dialects
+// that can map values to column types.
+type TypeMapper interface {
+	// ColumnType returns the type of a column holding values of the kind k.
+	// size is the maximum length of strings and byte slices, or 0 if not
+	// specified.
+	ColumnType(k ColumnKind, size int) string
+
+	// AutoIncrement returns the definition (without the name) of an
+	// auto-incremented integer primary key column of the type typ, e.g.
+	// "BIGINT NOT NULL AUTO_INCREMENT". If the definition declares the
+	// column as the primary key itself, such as "INTEGER PRIMARY KEY
+	// AUTOINCREMENT" in SQLite, inlinePK is true and the column is not
+	// included in the PRIMARY KEY constraint of the table.
+	AutoIncrement(typ string) (def string, inlinePK bool)
+}
+
+// ErrorKind is a dialect independent class of database errors.
+type ErrorKind int
+
+// The kinds of errors recognized by ErrorClassifier.
+const (
+	UnknownError ErrorKind = iota
+	DuplicateKey
+	ForeignKeyViolation
+	Deadlock
+	LockTimeout
+	ConnectionError
+)
+
+// ErrorClassifier is an optional interface implemented by dialects
+// that can classify errors returned by their drivers.
+type ErrorClassifier interface {
+	// ClassifyError returns the kind of err, or UnknownError
+	// if err is not recognized.
+	ClassifyError(err error) ErrorKind
+}
+
 // writeByte is a helper func for Dialect implementators.
 func writeByte(w io.Writer, b byte) (n int, err error) {
 	return w.Write([]byte{b})
@@ -1,9 +1,13 @@
 package dialect
 
 import (
+	"errors"
 	"io"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/go-sql-driver/mysql"
 )
 
 // MySQL is the implementation of Dialect for MySQL drivers.
@@ -83,3 +87,151 @@ func (mySQL) EscapeTime(w io.Writer, t time.Time) {
 func (mySQL) PrintPlaceholderSign(w io.Writer, n int) {
 	writeByte(w, '?')
 }
+
+// ReplicationLagQuery requires MySQL 8.0.22 or newer. Older versions
+// need "SHOW SLAVE STATUS" and "Seconds_Behind_Master" set using
+// dali.HealthCheck.
+func (mySQL) ReplicationLagQuery() (query, column string) {
+	return "SHOW REPLICA STATUS", "Seconds_Behind_Source"
+}
+
+func (mySQL) TablesQuery() string {
+	return "SELECT [TABLE_NAME] AS [name], [TABLE_TYPE] = 'VIEW' AS [view]" +
+		" FROM [information_schema].[TABLES] WHERE [TABLE_SCHEMA] = DATABASE()" +
+		" ORDER BY [TABLE_NAME]"
+}
+
+func (mySQL) ColumnsQuery() string {
+	return "SELECT [TABLE_NAME] AS [table], [COLUMN_NAME] AS [name], [DATA_TYPE] AS [data_type]," +
+		" [COLUMN_TYPE] AS [column_type], [IS_NULLABLE] = 'YES' AS [nullable]," +
+		" [COLUMN_KEY] = 'PRI' AS [primary_key], [EXTRA] LIKE '%auto_increment%' AS [auto_increment]" +
+		" FROM [information_schema].[COLUMNS] WHERE [TABLE_SCHEMA] = DATABASE() AND [TABLE_NAME] IN (?...)" +
+		" ORDER BY [TABLE_NAME], [ORDINAL_POSITION]"
+}
+
+func (mySQL) IndexesQuery() string {
+	return "SELECT [INDEX_NAME] AS [name], [NON_UNIQUE] = 0 AS [unique], [COLUMN_NAME] AS [column]" +
+		" FROM [information_schema].[STATISTICS] WHERE [TABLE_SCHEMA] = DATABASE() AND [TABLE_NAME] = ?" +
+		" ORDER BY [INDEX_NAME], [SEQ_IN_INDEX]"
+}
+
+func (mySQL) AdvisoryLockQueries() (lock, unlock string) {
+	return "SELECT GET_LOCK(?, -1)", "SELECT RELEASE_LOCK(?)"
+}
+
+// TransactionalDDL returns false as DDL statements cause
+// an implicit commit in MySQL.
+func (mySQL) TransactionalDDL() bool { return false }
+
+func (mySQL) ColumnType(k ColumnKind, size int) string {
+	switch k {
+	case BoolColumn:
+		return "BOOLEAN"
+	case IntColumn:
+		return "BIGINT"
+	case UintColumn:
+		return "BIGINT UNSIGNED"
+	case FloatColumn:
+		return "DOUBLE"
+	case StringColumn:
+		if size == 0 {
+			size = 255
+		}
+		return "VARCHAR(" + strconv.Itoa(size) + ")"
+	case BytesColumn:
+		if size == 0 {
+			return "BLOB"
+		}
+		return "VARBINARY(" + strconv.Itoa(size) + ")"
+	case TimeColumn:
+		return "DATETIME(6)"
+	}
+	panic("dialect: unknown column kind")
+}
+
+func (mySQL) AutoIncrement(typ string) (def string, inlinePK bool) {
+	return typ + " NOT NULL AUTO_INCREMENT", false
+}
+
+// SplitStatements splits script into statements terminated by
+// semicolons, skipping those in quotes and comments (--, #, and
+// /* */). Parts containing only comments are omitted. The DELIMITER
+// command of the mysql client is not supported.
+func (mySQL) SplitStatements(script string) []string {
+	var (
+		stmts   []string
+		start   int
+		hasCode bool
+		quote   byte
+	)
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote != '`' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		case c == '#', strings.HasPrefix(script[i:], "--") &&
+			(i+2 == len(script) || script[i+2] <= ' '):
+			if end := strings.IndexByte(script[i:], '\n'); end != -1 {
+				i += end
+			} else {
+				i = len(script)
+			}
+			continue
+		case strings.HasPrefix(script[i:], "/*"):
+			if end := strings.Index(script[i+2:], "*/"); end != -1 {
+				i += end + 3
+			} else {
+				i = len(script)
+			}
+			continue
+		case c == ';':
+			if hasCode {
+				stmts = append(stmts, strings.TrimSpace(script[start:i]))
+			}
+			start, hasCode = i+1, false
+			continue
+		case c == '\'', c == '"', c == '`':
+			quote = c
+		}
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+			hasCode = true
+		}
+	}
+	if hasCode {
+		stmts = append(stmts, strings.TrimSpace(script[start:]))
+	}
+	return stmts
+}
+
+// mysqlErrorKinds maps MySQL error numbers to error kinds. See
+// https://dev.mysql.com/doc/mysql-errors/8.0/en/ for the reference.
+var mysqlErrorKinds = map[uint16]ErrorKind{
+	1022: DuplicateKey,        // ER_DUP_KEY
+	1062: DuplicateKey,        // ER_DUP_ENTRY
+	1586: DuplicateKey,        // ER_DUP_ENTRY_WITH_KEY_NAME
+	1216: ForeignKeyViolation, // ER_NO_REFERENCED_ROW
+	1217: ForeignKeyViolation, // ER_ROW_IS_REFERENCED
+	1451: ForeignKeyViolation, // ER_ROW_IS_REFERENCED_2
+	1452: ForeignKeyViolation, // ER_NO_REFERENCED_ROW_2
+	1213: Deadlock,            // ER_LOCK_DEADLOCK
+	1205: LockTimeout,         // ER_LOCK_WAIT_TIMEOUT
+}
+
+// ClassifyError classifies errors of the github.com/go-sql-driver/mysql
+// driver: the server errors (*mysql.MySQLError) by their numbers and
+// mysql.ErrInvalidConn, which is returned when a connection is lost.
+func (mySQL) ClassifyError(err error) ErrorKind {
+	var myErr *mysql.MySQLError
+	switch {
+	case errors.As(err, &myErr):
+		return mysqlErrorKinds[myErr.Number]
+	case errors.Is(err, mysql.ErrInvalidConn):
+		return ConnectionError
+	}
+	return UnknownError
+}
@@ -2,6 +2,7 @@ package dialect
 
 import (
 	"bytes"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -110,3 +111,24 @@ func TestEscapeTime(t *testing.T) {
 		}
 	}
 }
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		script string
+		want   []string
+	}{
+		{"", nil},
+		{"SELECT 1", []string{"SELECT 1"}},
+		{" SELECT 1;\n\nSELECT 2;\n", []string{"SELECT 1", "SELECT 2"}},
+		{"SELECT ';', \";\", `;`, 'it\\';s';", []string{"SELECT ';', \";\", `;`, 'it\\';s'"}},
+		{"-- a; b\nSELECT 1; # c;\n/* d; */", []string{"-- a; b\nSELECT 1"}},
+		{"SELECT 2--1; SELECT 3", []string{"SELECT 2--1", "SELECT 3"}},
+		{";;SELECT 1;;", []string{"SELECT 1"}},
+	}
+	sp := MySQL.(StatementSplitter)
+	for _, tt := range tests {
+		if got := sp.SplitStatements(tt.script); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitStatements(%q) = %q, want %q", tt.script, got, tt.want)
+		}
+	}
+}
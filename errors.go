@@ -0,0 +1,122 @@
+package dali
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net"
+	"sync"
+	"syscall"
+
+	"github.com/mibk/dali/dialect"
+)
+
+var (
+	classifiersMu sync.RWMutex
+	classifiers   []dialect.ErrorClassifier
+)
+
+// RegisterErrorClassifier registers c to be used for classifying errors
+// by the package-level IsDuplicateKey and the other Is* functions, which,
+// unlike the methods of DB, don't know the dialect of the database the
+// error comes from. It is typically called in an init function:
+//
+//	dali.RegisterErrorClassifier(dialect.MySQL.(dialect.ErrorClassifier))
+func RegisterErrorClassifier(c dialect.ErrorClassifier) {
+	classifiersMu.Lock()
+	classifiers = append(classifiers, c)
+	classifiersMu.Unlock()
+}
+
+// ClassifyError returns the kind of err. The error is classified
+// by d, if it implements dialect.ErrorClassifier, then by the
+// registered classifiers. Errors of failed or lost connections
+// reported by database/sql and the operating system are recognized
+// regardless of the dialect. d may be nil.
+func ClassifyError(d dialect.Dialect, err error) dialect.ErrorKind {
+	if err == nil {
+		return dialect.UnknownError
+	}
+	if c, ok := d.(dialect.ErrorClassifier); ok {
+		if k := c.ClassifyError(err); k != dialect.UnknownError {
+			return k
+		}
+	}
+	classifiersMu.RLock()
+	for _, c := range classifiers {
+		if k := c.ClassifyError(err); k != dialect.UnknownError {
+			classifiersMu.RUnlock()
+			return k
+		}
+	}
+	classifiersMu.RUnlock()
+	var netErr *net.OpError
+	switch {
+	case errors.Is(err, driver.ErrBadConn),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.EPIPE),
+		errors.As(err, &netErr):
+		return dialect.ConnectionError
+	}
+	return dialect.UnknownError
+}
+
+// IsDuplicateKey reports whether err is caused by a violation
+// of a unique or primary key. The error is classified by the
+// registered classifiers; see RegisterErrorClassifier.
+func IsDuplicateKey(err error) bool {
+	return ClassifyError(nil, err) == dialect.DuplicateKey
+}
+
+// IsForeignKeyViolation reports whether err is caused by
+// a violation of a foreign key constraint.
+func IsForeignKeyViolation(err error) bool {
+	return ClassifyError(nil, err) == dialect.ForeignKeyViolation
+}
+
+// IsDeadlock reports whether err is caused by a deadlock.
+func IsDeadlock(err error) bool {
+	return ClassifyError(nil, err) == dialect.Deadlock
+}
+
+// IsLockTimeout reports whether err is caused by a timeout
+// of waiting for a lock.
+func IsLockTimeout(err error) bool {
+	return ClassifyError(nil, err) == dialect.LockTimeout
+}
+
+// IsConnectionError reports whether err is caused by a failed
+// or lost connection to the database.
+func IsConnectionError(err error) bool {
+	return ClassifyError(nil, err) == dialect.ConnectionError
+}
+
+// IsDuplicateKey is like the IsDuplicateKey function, but it
+// classifies err using the dialect of db.
+func (db *DB) IsDuplicateKey(err error) bool {
+	return ClassifyError(db.dialect, err) == dialect.DuplicateKey
+}
+
+// IsForeignKeyViolation is like the IsForeignKeyViolation function,
+// but it classifies err using the dialect of db.
+func (db *DB) IsForeignKeyViolation(err error) bool {
+	return ClassifyError(db.dialect, err) == dialect.ForeignKeyViolation
+}
+
+// IsDeadlock is like the IsDeadlock function, but it classifies
+// err using the dialect of db.
+func (db *DB) IsDeadlock(err error) bool {
+	return ClassifyError(db.dialect, err) == dialect.Deadlock
+}
+
+// IsLockTimeout is like the IsLockTimeout function, but it classifies
+// err using the dialect of db.
+func (db *DB) IsLockTimeout(err error) bool {
+	return ClassifyError(db.dialect, err) == dialect.LockTimeout
+}
+
+// IsConnectionError is like the IsConnectionError function, but it
+// classifies err using the dialect of db.
+func (db *DB) IsConnectionError(err error) bool {
+	return ClassifyError(db.dialect, err) == dialect.ConnectionError
+}
@@ -0,0 +1,105 @@
+package dali
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/mibk/dali/dialect"
+)
+
+func TestErrorClassification(t *testing.T) {
+	db := NewDB(nil, dialect.MySQL)
+	is := []struct {
+		name string
+		f    func(error) bool
+	}{
+		{"IsDuplicateKey", db.IsDuplicateKey},
+		{"IsForeignKeyViolation", db.IsForeignKeyViolation},
+		{"IsDeadlock", db.IsDeadlock},
+		{"IsLockTimeout", db.IsLockTimeout},
+		{"IsConnectionError", db.IsConnectionError},
+	}
+	tests := []struct {
+		err  error
+		want string // name of the func that should report true
+	}{
+		{&mysql.MySQLError{Number: 1062, Message: "Duplicate entry '1' for key 'PRIMARY'"}, "IsDuplicateKey"},
+		{fmt.Errorf("insert user: %w", &mysql.MySQLError{Number: 1452}), "IsForeignKeyViolation"},
+		{&mysql.MySQLError{Number: 1213}, "IsDeadlock"},
+		{&mysql.MySQLError{Number: 1205}, "IsLockTimeout"},
+		{errors.New("Error 1205 (HY000): Lock wait timeout exceeded"), ""},
+		{fmt.Errorf("query: %w", mysql.ErrInvalidConn), "IsConnectionError"},
+		{driver.ErrBadConn, "IsConnectionError"},
+		{fmt.Errorf("read: %w", syscall.ECONNRESET), "IsConnectionError"},
+		{&mysql.MySQLError{Number: 1146}, ""},
+		{errors.New("Error in query"), ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		for _, is := range is {
+			if got := is.f(tt.err); got != (is.name == tt.want) {
+				t.Errorf("%s(%v) = %v", is.name, tt.err, got)
+			}
+		}
+	}
+}
+
+// pgError mimics an error of a PostgreSQL driver.
+type pgError struct {
+	Code string // SQLSTATE
+}
+
+func (e *pgError) Error() string { return "pq: error " + e.Code }
+
+// pgClassifier classifies pgErrors by SQLSTATE.
+type pgClassifier struct{}
+
+func (pgClassifier) ClassifyError(err error) dialect.ErrorKind {
+	var pgErr *pgError
+	if !errors.As(err, &pgErr) {
+		return dialect.UnknownError
+	}
+	switch pgErr.Code {
+	case "23505":
+		return dialect.DuplicateKey
+	case "40P01":
+		return dialect.Deadlock
+	}
+	return dialect.UnknownError
+}
+
+func TestRegisterErrorClassifier(t *testing.T) {
+	// Only the dialect-independent errors are recognized
+	// by the package-level functions by default.
+	if IsDuplicateKey(&mysql.MySQLError{Number: 1062}) {
+		t.Error("MySQL error recognized by an unregistered classifier")
+	}
+	if !IsConnectionError(driver.ErrBadConn) {
+		t.Error("IsConnectionError(driver.ErrBadConn) = false")
+	}
+
+	dup := fmt.Errorf("insert: %w", &pgError{Code: "23505"})
+	if IsDuplicateKey(dup) {
+		t.Fatal("classified by an unregistered classifier")
+	}
+	RegisterErrorClassifier(pgClassifier{})
+	if !IsDuplicateKey(dup) {
+		t.Errorf("IsDuplicateKey(%v) = false", dup)
+	}
+	if !IsDeadlock(&pgError{Code: "40P01"}) || IsDeadlock(&pgError{Code: "23505"}) {
+		t.Error("wrong IsDeadlock")
+	}
+	// The dialect of a DB is still consulted first.
+	db := NewDB(nil, dialect.MySQL)
+	if !db.IsDuplicateKey(&mysql.MySQLError{Number: 1062}) {
+		t.Error("MySQL error not recognized")
+	}
+	if !db.IsDeadlock(&pgError{Code: "40P01"}) {
+		t.Error("registered classifier not used by DB")
+	}
+}
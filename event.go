@@ -0,0 +1,123 @@
+package dali
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/mibk/dali/dialect"
+)
+
+// Op is the kind of operation performed with a query.
+type Op int
+
+// The operations performed by the Query methods of the same name.
+const (
+	OpExec Op = iota + 1
+	OpRows
+	OpScanRow
+	OpScanAllRows
+	OpOne
+	OpAll
+
+	// The operations passed to hooks.
+	OpBegin
+	OpCommit
+	OpRollback
+	OpPrepare
+)
+
+var opNames = [...]string{
+	OpExec:        "Exec",
+	OpRows:        "Rows",
+	OpScanRow:     "ScanRow",
+	OpScanAllRows: "ScanAllRows",
+	OpOne:         "One",
+	OpAll:         "All",
+	OpBegin:       "Begin",
+	OpCommit:      "Commit",
+	OpRollback:    "Rollback",
+	OpPrepare:     "Prepare",
+}
+
+func (op Op) String() string {
+	if op > 0 && int(op) < len(opNames) {
+		return opNames[op]
+	}
+	return "Op(" + strconv.Itoa(int(op)) + ")"
+}
+
+// QueryEvent describes a query that is being executed. Middlewares
+// can obtain it from the context passed to the Execer methods using
+// QueryEventFromContext. Hooks receive it directly; for OpBegin, OpCommit,
+// and OpRollback, only the Op, Tx, and Dialect fields are set.
+type QueryEvent struct {
+	// Op is the Query method that executes the query, or
+	// the operation a hook is called for.
+	Op Op
+
+	// Template is the query as written by the user, before
+	// translation, and Args are the args passed along with it.
+	// For prepared statements, Args are the args used for
+	// building the statement; the bound args are passed to
+	// the Execer methods.
+	Template string
+	Args     []interface{}
+
+	// SQL is the translated query.
+	SQL string
+
+	// Prepared reports whether the query is a prepared statement.
+	Prepared bool
+
+	// Tx is the transaction the query is executed in, or nil.
+	Tx *Tx
+
+	// Conn is the connection the query is executed on, or nil
+	// if the connection is chosen by the pool.
+	Conn *Conn
+
+	// Idempotent reports whether the query was marked as safe
+	// to be executed repeatedly (see Query.Idempotent).
+	Idempotent bool
+
+	Dialect dialect.Dialect
+}
+
+type eventKey struct{}
+
+// QueryEventFromContext returns the QueryEvent describing the query
+// executed with ctx, if any.
+func QueryEventFromContext(ctx context.Context) (*QueryEvent, bool) {
+	ev, ok := ctx.Value(eventKey{}).(*QueryEvent)
+	return ev, ok
+}
+
+func contextWithEvent(ctx context.Context, ev *QueryEvent) context.Context {
+	return context.WithValue(ctx, eventKey{}, ev)
+}
+
+// A Hook is called before an operation that is not executed through
+// an Execer, and thus is not seen by middlewares (see DB.UseHooks).
+// The returned func, if not nil, is called with the result of
+// the operation.
+type Hook func(ctx context.Context, ev *QueryEvent) func(err error)
+
+// callHooks calls hooks for op described by ev and returns
+// a func reporting the result back to them.
+func callHooks(ctx context.Context, hooks []Hook, op Op, ev QueryEvent) func(error) {
+	if len(hooks) == 0 {
+		return func(error) {}
+	}
+	ev.Op = op
+	dones := make([]func(error), 0, len(hooks))
+	for _, h := range hooks {
+		if done := h(ctx, &ev); done != nil {
+			dones = append(dones, done)
+		}
+	}
+	return func(err error) {
+		for i := len(dones) - 1; i >= 0; i-- {
+			dones[i](err)
+		}
+	}
+}
@@ -1,3 +1,7 @@
 module github.com/mibk/dali
 
-go 1.20
+go 1.21
+
+require github.com/go-sql-driver/mysql v1.8.1
+
+require filippo.io/edwards25519 v1.1.0 // indirect
@@ -0,0 +1,98 @@
+// Package placeholder implements scanning and validation of
+// the placeholders of dali query templates. It is shared by
+// the translator, dali-gen, and dalivet.
+package placeholder
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Placeholder is a placeholder found in a query.
+type Placeholder struct {
+	Name   string // including the '?', e.g. "?ident..."
+	Offset int    // in bytes
+}
+
+// Error describes an invalid query template.
+type Error struct {
+	Placeholder string // e.g. "?u" or "[" for an identifier
+	Offset      int    // of the placeholder in bytes
+
+	// Index is the index of the placeholder among the placeholders
+	// preceding it, or -1 for an identifier.
+	Index int
+
+	Err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at offset %d: %v", e.Placeholder, e.Offset, e.Err)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// ErrUnterminatedIdent is reported for an identifier missing
+// the closing bracket.
+var ErrUnterminatedIdent = errors.New("identifier not terminated")
+
+// Scan scans the placeholder following the '?' that precedes pos.
+// It returns the type of the placeholder, whether it is expanded,
+// and the position following the placeholder.
+func Scan(sql string, pos int) (typ string, expand bool, next int) {
+	start := pos
+	for pos < len(sql) && sql[pos] >= 'a' && sql[pos] <= 'z' {
+		pos++
+	}
+	typ = sql[start:pos]
+	if strings.HasPrefix(sql[pos:], "...") {
+		return typ, true, pos + 3
+	}
+	return typ, false, pos
+}
+
+// Check reports whether the placeholder of type typ exists.
+func Check(typ string, expand bool) error {
+	switch typ {
+	case "", "ident", "values":
+		return nil
+	case "set", "sql":
+		if !expand {
+			return nil
+		}
+	}
+	if expand {
+		return fmt.Errorf("?%s cannot be expanded (...) or doesn't exist", typ)
+	}
+	return fmt.Errorf("unknown placeholder ?%s", typ)
+}
+
+// Parse returns the placeholders contained in query in the order
+// of appearance, so that each of them (except ? in prepared
+// statements) corresponds to an arg. It reports unterminated
+// identifiers and unknown placeholders using *Error.
+func Parse(query string) ([]Placeholder, error) {
+	var phs []Placeholder
+	for pos := 0; pos < len(query); {
+		switch query[pos] {
+		case '[':
+			w := strings.IndexByte(query[pos:], ']')
+			if w == -1 {
+				return nil, &Error{Placeholder: "[", Offset: pos, Index: -1, Err: ErrUnterminatedIdent}
+			}
+			pos += w + 1
+		case '?':
+			typ, expand, next := Scan(query, pos+1)
+			ph := Placeholder{Name: query[pos:next], Offset: pos}
+			if err := Check(typ, expand); err != nil {
+				return nil, &Error{Placeholder: ph.Name, Offset: pos, Index: len(phs), Err: err}
+			}
+			phs = append(phs, ph)
+			pos = next
+		default:
+			pos++
+		}
+	}
+	return phs, nil
+}
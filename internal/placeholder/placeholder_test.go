@@ -0,0 +1,45 @@
+package placeholder
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want []Placeholder
+		err  string
+	}{
+		{"SELECT [a?] FROM ?ident WHERE [id] IN (?...) AND b = ?",
+			[]Placeholder{{"?ident", 17}, {"?...", 39}, {"?", 53}}, ""},
+		{"INSERT ?values...; UPDATE ?set; ?sql", []Placeholder{{"?values...", 7}, {"?set", 26}, {"?sql", 32}}, ""},
+		{"SELECT [a", nil, "[ at offset 7: identifier not terminated"},
+		{"SELECT ?u", nil, "?u at offset 7: unknown placeholder ?u"},
+		{"SELECT ?, ?set...", nil, "?set... at offset 10: ?set cannot be expanded (...) or doesn't exist"},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.sql)
+		var gotErr string
+		if err != nil {
+			gotErr = err.Error()
+		}
+		if gotErr != tt.err {
+			t.Errorf("%s:\ngot err: %v\n   want: %v", tt.sql, gotErr, tt.err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got: %v\nwant: %v", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestErrorIndex(t *testing.T) {
+	_, err := Parse("SELECT ?, [a], ?ident... FROM ?x")
+	e, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("got %v, want *Error", err)
+	}
+	if e.Placeholder != "?x" || e.Offset != 30 || e.Index != 2 {
+		t.Errorf("got %q at %d (index %d), want \"?x\" at 30 (index 2)", e.Placeholder, e.Offset, e.Index)
+	}
+}
@@ -0,0 +1,22 @@
+// Package sqlfile implements helpers for reading queries
+// from SQL files.
+package sqlfile
+
+import "strings"
+
+// TrimQuery trims the body of a query read from an SQL file. Besides
+// surrounding white space, it removes trailing lines containing only
+// comments, which belong to the following query (e.g. its description),
+// and the semicolon terminating the query.
+func TrimQuery(body string) string {
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	for len(lines) > 0 {
+		l := strings.TrimSpace(lines[len(lines)-1])
+		if l != "" && !strings.HasPrefix(l, "--") {
+			break
+		}
+		lines = lines[:len(lines)-1]
+	}
+	q := strings.TrimSpace(strings.Join(lines, "\n"))
+	return strings.TrimSpace(strings.TrimSuffix(q, ";"))
+}
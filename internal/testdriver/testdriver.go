@@ -0,0 +1,195 @@
+// Package testdriver implements a fake database/sql driver. The
+// operations of the database are served by a Handler: Driver serves
+// canned results in the tests of dali and its subpackages, and package
+// dalitest implements its fake databases as Handlers as well.
+package testdriver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+)
+
+// A Handler serves the operations of a fake database. Handlers
+// may implement Pinger as well.
+type Handler interface {
+	Begin() error
+	Commit() error
+	Rollback() error
+	Exec(query string, args []driver.Value) (driver.Result, error)
+	Query(query string, args []driver.Value) (driver.Rows, error)
+}
+
+// Pinger is implemented by Handlers whose pings can fail.
+type Pinger interface {
+	Ping() error
+}
+
+// Open returns a DB handle of a fake database served by h.
+func Open(h Handler) *sql.DB {
+	return sql.OpenDB(connector{h})
+}
+
+type connector struct{ h Handler }
+
+func (c connector) Connect(context.Context) (driver.Conn, error) { return conn(c), nil }
+func (c connector) Driver() driver.Driver                        { return c }
+func (c connector) Open(string) (driver.Conn, error)             { return conn(c), nil }
+
+type conn struct{ h Handler }
+
+func (c conn) Prepare(query string) (driver.Stmt, error) { return stmt{c.h, query}, nil }
+func (conn) Close() error                                { return nil }
+
+func (c conn) Begin() (driver.Tx, error) {
+	if err := c.h.Begin(); err != nil {
+		return nil, err
+	}
+	return tx(c), nil
+}
+
+func (c conn) Ping(context.Context) error {
+	if p, ok := c.h.(Pinger); ok {
+		return p.Ping()
+	}
+	return nil
+}
+
+type stmt struct {
+	h     Handler
+	query string
+}
+
+func (stmt) Close() error  { return nil }
+func (stmt) NumInput() int { return -1 }
+
+func (s stmt) Exec(args []driver.Value) (driver.Result, error) { return s.h.Exec(s.query, args) }
+func (s stmt) Query(args []driver.Value) (driver.Rows, error)  { return s.h.Query(s.query, args) }
+
+type tx struct{ h Handler }
+
+func (t tx) Commit() error   { return t.h.Commit() }
+func (t tx) Rollback() error { return t.h.Rollback() }
+
+// NewRows returns driver rows with the columns cols, which
+// return the values of rows.
+func NewRows(cols []string, rows [][]driver.Value) driver.Rows {
+	return &rowsIter{cols: cols, rows: rows}
+}
+
+type rowsIter struct {
+	cols []string
+	rows [][]driver.Value
+}
+
+func (r *rowsIter) Columns() []string { return r.cols }
+func (r *rowsIter) Close() error      { return nil }
+
+func (r *rowsIter) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// Driver is a Handler serving the same canned result for every query.
+// It records the queries it receives, including COMMIT and ROLLBACK,
+// so that they can be made to fail using FailNext.
+type Driver struct {
+	mu           sync.Mutex
+	columns      []string
+	rows         [][]driver.Value
+	err          error
+	failures     []error
+	rowsAffected int64
+	queries      []string
+}
+
+// New returns a new Driver together with a DB handle using it.
+func New() (*Driver, *sql.DB) {
+	d := new(Driver)
+	return d, Open(d)
+}
+
+// SetRows sets the result of queries returning rows.
+func (d *Driver) SetRows(cols []string, rows ...[]driver.Value) {
+	d.mu.Lock()
+	d.columns, d.rows = cols, rows
+	d.mu.Unlock()
+}
+
+// SetRowsAffected sets the number of rows affected reported
+// by executed statements.
+func (d *Driver) SetRowsAffected(n int64) {
+	d.mu.Lock()
+	d.rowsAffected = n
+	d.mu.Unlock()
+}
+
+// SetErr makes all subsequent queries and pings fail with err.
+func (d *Driver) SetErr(err error) {
+	d.mu.Lock()
+	d.err = err
+	d.mu.Unlock()
+}
+
+// FailNext makes the next len(errs) queries fail with errs,
+// one by one.
+func (d *Driver) FailNext(errs ...error) {
+	d.mu.Lock()
+	d.failures = append(d.failures, errs...)
+	d.mu.Unlock()
+}
+
+// Queries returns the queries received so far and forgets them.
+func (d *Driver) Queries() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	q := d.queries
+	d.queries = nil
+	return q
+}
+
+func (d *Driver) record(query string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queries = append(d.queries, query)
+	if len(d.failures) > 0 {
+		err := d.failures[0]
+		d.failures = d.failures[1:]
+		return err
+	}
+	return d.err
+}
+
+func (d *Driver) Begin() error    { return nil }
+func (d *Driver) Commit() error   { return d.record("COMMIT") }
+func (d *Driver) Rollback() error { return d.record("ROLLBACK") }
+
+func (d *Driver) Ping() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.err
+}
+
+func (d *Driver) Exec(query string, args []driver.Value) (driver.Result, error) {
+	if err := d.record(query); err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return driver.RowsAffected(d.rowsAffected), nil
+}
+
+func (d *Driver) Query(query string, args []driver.Value) (driver.Rows, error) {
+	if err := d.record(query); err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return NewRows(d.columns, d.rows), nil
+}
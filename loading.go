@@ -20,7 +20,7 @@ func (q *Query) One(dest interface{}) error {
 	if v.Kind() != reflect.Struct {
 		panic("dali: dest must be a pointer to a struct")
 	}
-	return q.loadStruct(v)
+	return q.load(OpOne, v, v.Type(), true, false)
 }
 
 // All executes the query that should return rows, and loads the
@@ -47,19 +47,13 @@ func (q *Query) All(dest interface{}) error {
 	case reflect.Ptr:
 		panic("dali: a pointer to a pointer is not allowed as an element of dest")
 	case reflect.Struct:
-		return q.loadStructs(slicev, elemt, isPtr)
+		return q.load(OpAll, slicev, elemt, false, isPtr)
 	}
 	panic(errMsg)
 }
 
-func (q *Query) loadStruct(v reflect.Value) error { return q.load(v, v.Type(), true, false) }
-
-func (q *Query) loadStructs(slicev reflect.Value, elemt reflect.Type, isPtr bool) error {
-	return q.load(slicev, elemt, false, isPtr)
-}
-
-func (q *Query) load(v reflect.Value, elemt reflect.Type, loadJustOne, isPtr bool) error {
-	rows, err := q.Rows()
+func (q *Query) load(op Op, v reflect.Value, elemt reflect.Type, loadJustOne, isPtr bool) error {
+	rows, err := q.rows(op)
 	if err != nil {
 		return err
 	}
@@ -143,7 +137,7 @@ func (q *Query) ScanAllRows(dests ...interface{}) error {
 		}
 		elemtypes[i] = slicevals[i].Type().Elem()
 	}
-	rows, err := q.Rows()
+	rows, err := q.rows(OpScanAllRows)
 	if err != nil {
 		return err
 	}
@@ -13,13 +13,9 @@ var (
 )
 
 func init() {
-	dvr = NewFakeDialect()
-	sql.Register("dali", dvr)
-	dbHandle, err := sql.Open("dali", "")
-	if err != nil {
-		panic(err)
-	}
-	db = NewDB(dbHandle, dvr)
+	var handle *sql.DB
+	dvr, handle = NewFakeDialect()
+	db = NewDB(handle, dvr)
 }
 
 func TestScanRow(t *testing.T) {
@@ -99,7 +95,7 @@ func Test_One_and_All(t *testing.T) {
 			parseTime("2015-05-05 12:24:32"), "2015-05-05 13:08:17"}),
 			newTypeOf(SpecialStruct{}), (*Query).One,
 			SpecialStruct{"Lunch", parseTime("2015-05-05 12:24:32"),
-				sql.NullString{"2015-05-05 13:08:17", true}}},
+				sql.NullString{String: "2015-05-05 13:08:17", Valid: true}}},
 
 		// ignore scanner but not valuer
 		{cols("A", "B", "Scan"), result(VSres{2, 3, "group:name"}),
@@ -3,6 +3,7 @@ package dali
 import (
 	"context"
 	"database/sql"
+	"reflect"
 	"testing"
 )
 
@@ -47,6 +48,169 @@ func TestMiddleware(t *testing.T) {
 	checkmw("#4-queryrow")
 }
 
+func TestMiddlewareChain(t *testing.T) {
+	db := NewDB(db.DB, dvr)
+	var order []string
+	mw := func(name string) Middleware {
+		return func(e Execer) Execer {
+			return &namedMiddle{e, name, &order}
+		}
+	}
+	db.Use(mw("log"))
+	db.Use(mw("metrics"), mw("trace"))
+
+	db.Query("").Exec()
+	tx := db.mustBegin()
+	tx.Query("").Exec()
+	db.mustPrepare("").Bind().Exec()
+
+	want := []string{
+		"log", "metrics", "trace",
+		"log", "metrics", "trace",
+		"log", "metrics", "trace",
+	}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("\n got: %v\nwant: %v", order, want)
+	}
+
+	order = nil
+	db.SetMiddlewareFunc(mw("only"))
+	db.Query("").Exec()
+	tx.Query("").Exec()
+	want = []string{"only", "log", "metrics", "trace"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("\n got: %v\nwant: %v", order, want)
+	}
+}
+
+func TestQueryEvent(t *testing.T) {
+	db := NewDB(db.DB, dvr)
+	var got *QueryEvent
+	db.Use(func(e Execer) Execer {
+		return &eventMiddle{e, &got}
+	})
+	dvr.SetColumns("ID", "Name").SetResult(U{1, "John"})
+
+	check := func(want QueryEvent) {
+		t.Helper()
+		if got == nil {
+			t.Fatalf("%v: no event", want.Op)
+		}
+		if !reflect.DeepEqual(*got, want) {
+			t.Errorf("\n got: %+v\nwant: %+v", *got, want)
+		}
+		got = nil
+	}
+
+	const tmpl = "SELECT * FROM ?ident WHERE [id] = ?"
+	args := Args{"user", 1}
+	const sql = "SELECT * FROM {user} WHERE {id} = 1"
+	ev := QueryEvent{Template: tmpl, Args: args, SQL: sql, Dialect: dvr}
+	var u U
+	var us []U
+	var ids []int64
+	for _, tt := range []struct {
+		op Op
+		fn func(q *Query)
+	}{
+		{OpExec, func(q *Query) { q.Exec() }},
+		{OpRows, func(q *Query) { q.Rows() }},
+		{OpScanRow, func(q *Query) { q.ScanRow(&u.ID, &u.Name) }},
+		{OpScanAllRows, func(q *Query) { q.ScanAllRows(&ids) }},
+		{OpOne, func(q *Query) { q.One(&u) }},
+		{OpAll, func(q *Query) { q.All(&us) }},
+	} {
+		tt.fn(db.Query(tmpl, args...))
+		ev.Op = tt.op
+		check(ev)
+	}
+
+	tx := db.mustBegin()
+	tx.Query(tmpl, args...).Exec()
+	check(QueryEvent{Op: OpExec, Template: tmpl, Args: args, SQL: sql, Tx: tx, Dialect: dvr})
+
+	const prepTmpl = "SELECT ?ident WHERE [id] = ?"
+	tx.mustPrepare(prepTmpl, "name").Bind(1).Exec()
+	check(QueryEvent{Op: OpExec, Template: prepTmpl, Args: Args{"name"},
+		SQL: "SELECT {name} WHERE {id} = &1", Prepared: true, Tx: tx, Dialect: dvr})
+
+	tx.Stmt(db.mustPrepare(prepTmpl, "name")).Bind(1).Rows()
+	check(QueryEvent{Op: OpRows, Template: prepTmpl, Args: Args{"name"},
+		SQL: "SELECT {name} WHERE {id} = &1", Prepared: true, Tx: tx, Dialect: dvr})
+}
+
+func TestHooks(t *testing.T) {
+	db := NewDB(db.DB, dvr)
+	var calls []string
+	db.UseHooks(func(ctx context.Context, ev *QueryEvent) func(error) {
+		calls = append(calls, ev.Op.String()+":"+ev.Template)
+		return func(err error) {
+			calls = append(calls, ev.Op.String()+" done")
+		}
+	})
+	db.UseHooks(func(ctx context.Context, ev *QueryEvent) func(error) {
+		if (ev.Op == OpCommit || ev.Op == OpRollback) && ev.Tx == nil {
+			t.Errorf("%v: missing tx", ev.Op)
+		}
+		return nil
+	})
+
+	db.mustPrepare("SELECT ?")
+	tx := db.mustBegin()
+	tx.mustPrepare("SELECT 1")
+	tx.Query("SELECT 2").Exec()
+	tx.Commit()
+	db.mustBegin().Rollback()
+
+	want := []string{
+		"Prepare:SELECT ?", "Prepare done",
+		"Begin:", "Begin done",
+		"Prepare:SELECT 1", "Prepare done",
+		"Commit:", "Commit done",
+		"Begin:", "Begin done",
+		"Rollback:", "Rollback done",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("\n got: %v\nwant: %v", calls, want)
+	}
+}
+
+type eventMiddle struct {
+	Execer
+	ev **QueryEvent
+}
+
+func (m *eventMiddle) event(ctx context.Context) {
+	ev, _ := QueryEventFromContext(ctx)
+	*m.ev = ev
+}
+
+func (m *eventMiddle) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	m.event(ctx)
+	return m.Execer.ExecContext(ctx, query, args...)
+}
+
+func (m *eventMiddle) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	m.event(ctx)
+	return m.Execer.QueryContext(ctx, query, args...)
+}
+
+func (m *eventMiddle) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	m.event(ctx)
+	return m.Execer.QueryRowContext(ctx, query, args...)
+}
+
+type namedMiddle struct {
+	Execer
+	name  string
+	calls *[]string
+}
+
+func (t *namedMiddle) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	*t.calls = append(*t.calls, t.name)
+	return t.Execer.ExecContext(ctx, query, args...)
+}
+
 type middle struct {
 	ex    Execer
 	lastq string
@@ -0,0 +1,371 @@
+// Package migrate applies schema migrations using dali.
+//
+// Migrations are SQL files in an fs.FS named VERSION_NAME.up.sql and
+// VERSION_NAME.down.sql, where VERSION is a positive integer:
+//
+//	0001_create_user.up.sql
+//	0001_create_user.down.sql
+//	0002_add_user_email.up.sql
+//
+// A file may contain several statements terminated by semicolons if
+// the dialect implements dialect.StatementSplitter; otherwise, the whole
+// file is executed as a single statement. Since the splitting doesn't
+// understand compound statements, such as trigger or procedure bodies
+// delimited by BEGIN and END, files containing them must start with
+// the directive
+//
+//	-- migrate:nosplit
+//
+// and contain just one statement. The statements are executed as they
+// are, without being translated by dali. The applied versions are
+// recorded in a table.
+//
+//	//go:embed migrations/*.sql
+//	var migrations embed.FS
+//
+//	fsys, _ := fs.Sub(migrations, "migrations")
+//	m, err := migrate.New(db, fsys, nil)
+//	if err != nil {
+//		return err
+//	}
+//	err = m.Up(ctx)
+//
+// If the dialect implements dialect.AdvisoryLocker, a lock is held while
+// migrating to prevent concurrent runs. If it implements dialect.DDLTransactor
+// reporting that DDL statements are transactional, each migration runs in
+// a transaction together with recording its version. Otherwise, a migration
+// failing midway leaves the database partially migrated and must be fixed
+// manually.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mibk/dali"
+	"github.com/mibk/dali/dialect"
+)
+
+// Migration is a single schema change.
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      string
+	Down    string // empty if the migration cannot be reverted
+
+	upFile, downFile string
+}
+
+// Options configure a Migrator. A nil *Options is equivalent
+// to the zero value.
+type Options struct {
+	// Table is the name of the table with the applied versions.
+	// Defaults to "dali_migrations".
+	Table string
+
+	// LockName is the name of the advisory lock.
+	// Defaults to "dali_migrate".
+	LockName string
+
+	// DryRun, if not nil, makes the Migrator write the statements
+	// to DryRun instead of executing them. Nothing is changed in the
+	// database, including the versions table. If the versions table
+	// cannot be read, no migration is considered applied.
+	DryRun io.Writer
+}
+
+// Migrator applies migrations to a database.
+type Migrator struct {
+	db         *dali.DB
+	dialect    dialect.Dialect
+	migrations []Migration
+	opts       Options
+}
+
+// New returns a Migrator applying the migrations in fsys (see Load)
+// to db.
+func New(db *dali.DB, fsys fs.FS, opts *Options) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	m := &Migrator{db: db, dialect: db.Dialect(), migrations: migrations}
+	if opts != nil {
+		m.opts = *opts
+	}
+	if m.opts.Table == "" {
+		m.opts.Table = "dali_migrations"
+	}
+	if m.opts.LockName == "" {
+		m.opts.LockName = "dali_migrate"
+	}
+	return m, nil
+}
+
+var fileRx = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Load loads the migrations from the files in the root of fsys
+// ordered by their versions. Other files are ignored.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	byVersion := make(map[uint64]*Migration)
+	for _, e := range entries {
+		m := fileRx.FindStringSubmatch(e.Name())
+		if m == nil || e.IsDir() {
+			continue
+		}
+		version, err := strconv.ParseUint(m[1], 10, 64)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("migrate: %s: invalid version", e.Name())
+		}
+		b, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("migrate: %w", err)
+		}
+		mig := byVersion[version]
+		if mig == nil {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migrate: version %d used by %s and %s", version, mig.Name, m[2])
+		}
+		script, file := &mig.Up, &mig.upFile
+		if m[3] == "down" {
+			script, file = &mig.Down, &mig.downFile
+		}
+		if *file != "" {
+			return nil, fmt.Errorf("migrate: version %d has two %s migrations: %s and %s", version, m[3], *file, e.Name())
+		}
+		*script, *file = string(b), e.Name()
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migrate: missing up migration %d_%s", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Migrations returns the loaded migrations.
+func (m *Migrator) Migrations() []Migration {
+	return append([]Migration(nil), m.migrations...)
+}
+
+// Applied returns the applied versions in ascending order.
+func (m *Migrator) Applied(ctx context.Context) ([]uint64, error) {
+	return m.applied(ctx, m.db)
+}
+
+func (m *Migrator) applied(ctx context.Context, q dali.Querier) ([]uint64, error) {
+	var rows []struct {
+		Version uint64 `db:"version"`
+	}
+	err := q.QueryWithContext(ctx, "SELECT [version] FROM ?ident ORDER BY [version]", m.opts.Table).All(&rows)
+	if err != nil {
+		return nil, fmt.Errorf("migrate: reading applied versions: %w", err)
+	}
+	versions := make([]uint64, len(rows))
+	for i, r := range rows {
+		versions[i] = r.Version
+	}
+	return versions, nil
+}
+
+// Up applies all pending migrations in the order of their versions.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.run(ctx, func(conn *dali.Conn, applied map[uint64]bool) error {
+		for _, mig := range m.migrations {
+			if applied[mig.Version] {
+				continue
+			}
+			if err := m.apply(ctx, conn, mig, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Down reverts the last applied migration. It does nothing
+// if no migration has been applied.
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.run(ctx, func(conn *dali.Conn, applied map[uint64]bool) error {
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mig := m.migrations[i]
+			if !applied[mig.Version] {
+				continue
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("migrate: migration %d_%s cannot be reverted", mig.Version, mig.Name)
+			}
+			return m.apply(ctx, conn, mig, false)
+		}
+		return nil
+	})
+}
+
+// run calls f with the applied versions while holding the lock.
+// All statements are executed on a single connection, which holds
+// the lock and keeps the session settings of the migrations.
+// In dry run, f is called with a nil connection.
+func (m *Migrator) run(ctx context.Context, f func(conn *dali.Conn, applied map[uint64]bool) error) error {
+	if m.opts.DryRun != nil {
+		versions, _ := m.Applied(ctx)
+		return f(nil, versionSet(versions))
+	}
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	defer conn.Close()
+	unlock, err := m.lock(ctx, conn)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := m.createTable(ctx, conn); err != nil {
+		return err
+	}
+	versions, err := m.applied(ctx, conn)
+	if err != nil {
+		return err
+	}
+	return f(conn, versionSet(versions))
+}
+
+func versionSet(versions []uint64) map[uint64]bool {
+	set := make(map[uint64]bool)
+	for _, v := range versions {
+		set[v] = true
+	}
+	return set
+}
+
+// lock acquires the advisory lock on conn, if supported by
+// the dialect, and returns the function releasing it.
+func (m *Migrator) lock(ctx context.Context, conn *dali.Conn) (unlock func(), err error) {
+	l, ok := m.dialect.(dialect.AdvisoryLocker)
+	if !ok {
+		return func() {}, nil
+	}
+	lockQuery, unlockQuery := l.AdvisoryLockQueries()
+	var acquired sql.NullBool
+	if err := conn.QueryWithContext(ctx, lockQuery, m.opts.LockName).ScanRow(&acquired); err != nil {
+		return nil, fmt.Errorf("migrate: acquiring lock: %w", err)
+	}
+	if !acquired.Bool {
+		return nil, fmt.Errorf("migrate: lock %s not acquired", m.opts.LockName)
+	}
+	return func() {
+		// Closing the connection would release the lock as well,
+		// but the pool keeps it open.
+		var released sql.NullBool
+		conn.QueryWithContext(context.Background(), unlockQuery, m.opts.LockName).ScanRow(&released)
+	}, nil
+}
+
+func (m *Migrator) createTable(ctx context.Context, conn *dali.Conn) error {
+	_, err := conn.QueryWithContext(ctx, "CREATE TABLE IF NOT EXISTS ?ident ("+
+		"[version] BIGINT NOT NULL PRIMARY KEY, "+
+		"[name] VARCHAR(255) NOT NULL, "+
+		"[applied_at] TIMESTAMP NOT NULL)", m.opts.Table).Exec()
+	if err != nil {
+		return fmt.Errorf("migrate: creating versions table: %w", err)
+	}
+	return nil
+}
+
+// apply executes the up or down part of mig on conn and records
+// the change.
+func (m *Migrator) apply(ctx context.Context, conn *dali.Conn, mig Migration, up bool) (err error) {
+	script, file := mig.Up, mig.upFile
+	if !up {
+		script, file = mig.Down, mig.downFile
+	}
+	stmts := m.statements(script)
+	if w := m.opts.DryRun; w != nil {
+		fmt.Fprintf(w, "-- %s\n", file)
+		for _, s := range stmts {
+			fmt.Fprintf(w, "%s;\n", s)
+		}
+		fmt.Fprintln(w)
+		return nil
+	}
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("migrate: %s: %w", file, err)
+		}
+	}()
+
+	var (
+		q  dali.Querier = conn
+		tx *dali.Tx
+	)
+	if d, ok := m.dialect.(dialect.DDLTransactor); ok && d.TransactionalDDL() {
+		if tx, err = conn.BeginTx(ctx, nil); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+			}
+		}()
+		q = tx
+	}
+	for _, s := range stmts {
+		if _, err := q.QueryWithContext(ctx, "?sql", s).Exec(); err != nil {
+			return err
+		}
+	}
+	if up {
+		_, err = q.QueryWithContext(ctx, "INSERT INTO ?ident ?values", m.opts.Table, dali.Map{
+			"version":    mig.Version,
+			"name":       mig.Name,
+			"applied_at": time.Now().UTC(),
+		}).Exec()
+	} else {
+		_, err = q.QueryWithContext(ctx, "DELETE FROM ?ident WHERE [version] = ?", m.opts.Table, mig.Version).Exec()
+	}
+	if err != nil {
+		return err
+	}
+	if tx != nil {
+		return tx.Commit()
+	}
+	return nil
+}
+
+// noSplit is the directive making a migration file
+// a single statement.
+const noSplit = "-- migrate:nosplit"
+
+// statements returns the statements of script. The script is split
+// by the dialect, unless it starts with the noSplit directive.
+func (m *Migrator) statements(script string) []string {
+	sp, ok := m.dialect.(dialect.StatementSplitter)
+	if first, _, _ := strings.Cut(strings.TrimSpace(script), "\n"); ok && strings.TrimSpace(first) != noSplit {
+		return sp.SplitStatements(script)
+	}
+	s := strings.TrimSpace(script)
+	s = strings.TrimSpace(strings.TrimSuffix(s, ";"))
+	if s == "" {
+		return nil
+	}
+	return []string{s}
+}
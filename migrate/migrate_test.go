@@ -0,0 +1,261 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/mibk/dali/dalitest"
+	"github.com/mibk/dali/dialect"
+)
+
+var migrations = fstest.MapFS{
+	"0001_create_user.up.sql":     {Data: []byte("CREATE TABLE `user` (`id` INT);\n-- The end.\n")},
+	"0001_create_user.down.sql":   {Data: []byte("DROP TABLE `user`;")},
+	"0002_add_email.up.sql":       {Data: []byte("ALTER TABLE `user` ADD `email` TEXT;\nCREATE INDEX `email` ON `user` (`email`(20));")},
+	"0002_add_email.down.sql":     {Data: []byte("ALTER TABLE `user` DROP `email`")},
+	"0010_seed.up.sql":            {Data: []byte("INSERT INTO `user` VALUES (1);")},
+	"README.md":                   {Data: []byte("Not a migration.")},
+	"0003_not_migration.sql.orig": {Data: []byte("Ignored.")},
+}
+
+func TestLoad(t *testing.T) {
+	migs, err := Load(migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, m := range migs {
+		got = append(got, m.Name)
+	}
+	if want := []string{"create_user", "add_email", "seed"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if migs[2].Version != 10 || migs[2].Down != "" {
+		t.Errorf("unexpected seed migration: %+v", migs[2])
+	}
+
+	tests := []struct {
+		files []string
+		err   string
+	}{
+		{[]string{"1_a.down.sql"}, "migrate: missing up migration 1_a"},
+		{[]string{"1_a.up.sql", "1_b.up.sql"}, "migrate: version 1 used by a and b"},
+		{[]string{"0_a.up.sql"}, "migrate: 0_a.up.sql: invalid version"},
+		{[]string{"1_a.up.sql", "0001_a.up.sql"}, "migrate: version 1 has two up migrations: 0001_a.up.sql and 1_a.up.sql"},
+	}
+	for _, tt := range tests {
+		fsys := make(fstest.MapFS)
+		for _, f := range tt.files {
+			fsys[f] = &fstest.MapFile{Data: []byte("SELECT 1")}
+		}
+		_, err := Load(fsys)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("%q: got %v, want %s", tt.files, err, tt.err)
+		}
+	}
+}
+
+func TestStatements(t *testing.T) {
+	trigger := "-- migrate:nosplit\n" +
+		"CREATE TRIGGER `t` BEFORE INSERT ON `user` FOR EACH ROW\n" +
+		"BEGIN\n\tSET NEW.`a` = 1;\n\tSET NEW.`b` = 2;\nEND;\n"
+	function := "CREATE FUNCTION one() RETURNS int AS $$\n" +
+		"BEGIN\n\tRETURN 1; # Not a comment.\nEND;\n$$ LANGUAGE plpgsql;"
+	tests := []struct {
+		d      dialect.Dialect
+		script string
+		want   []string
+	}{
+		{dialect.MySQL, "SELECT 1; # a;\nSELECT 2;", []string{"SELECT 1", "# a;\nSELECT 2"}},
+		{dialect.MySQL, trigger, []string{strings.TrimSuffix(trigger, ";\n")}},
+		// Dialects without StatementSplitter.
+		{plain{dialect.MySQL}, function, []string{strings.TrimSuffix(function, ";")}},
+		{plain{dialect.MySQL}, "\n-- Nothing.\n;", []string{"-- Nothing."}},
+		{plain{dialect.MySQL}, " ;\n", nil},
+	}
+	for _, tt := range tests {
+		m := &Migrator{dialect: tt.d}
+		if got := m.statements(tt.script); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%T: statements(%q) = %q, want %q", tt.d, tt.script, got, tt.want)
+		}
+	}
+}
+
+const (
+	createTable = "CREATE TABLE IF NOT EXISTS `dali_migrations` (`version` BIGINT NOT NULL PRIMARY KEY, " +
+		"`name` VARCHAR(255) NOT NULL, `applied_at` TIMESTAMP NOT NULL)"
+	selectApplied = "SELECT `version` FROM `dali_migrations` ORDER BY `version`"
+)
+
+func expectLock(mock *dalitest.Mock) {
+	mock.ExpectQuery("SELECT GET_LOCK('dali_migrate', -1)").
+		WillReturnRows(dalitest.NewRows("lock").AddRow(1))
+}
+
+func expectUnlock(mock *dalitest.Mock) {
+	mock.ExpectQuery("SELECT RELEASE_LOCK('dali_migrate')").
+		WillReturnRows(dalitest.NewRows("lock").AddRow(1))
+}
+
+func TestUp(t *testing.T) {
+	db, mock := dalitest.New(dialect.MySQL)
+	expectLock(mock)
+	mock.ExpectExec(createTable)
+	mock.ExpectQuery(selectApplied).WillReturnRows(dalitest.NewRows("version").AddRow(1))
+	mock.ExpectExec("ALTER TABLE `user` ADD `email` TEXT")
+	mock.ExpectExec("CREATE INDEX `email` ON `user` (`email`(20))")
+	mock.ExpectExecRegexp("^INSERT INTO `dali_migrations` \\(`applied_at`, `name`, `version`\\) VALUES \\('.*', 'add_email', 2\\)$")
+	mock.ExpectExec("INSERT INTO `user` VALUES (1)").WillReturnError(errors.New("duplicate"))
+	expectUnlock(mock)
+
+	m, err := New(db, migrations, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.Up(context.Background())
+	if err == nil || err.Error() != "migrate: 0010_seed.up.sql: duplicate" {
+		t.Errorf("got %v, want seed failure", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUpSingleConn(t *testing.T) {
+	db, mock := dalitest.New(dialect.MySQL)
+	db.DB.SetMaxOpenConns(1)
+	expectLock(mock)
+	mock.ExpectExec(createTable)
+	mock.ExpectQuery(selectApplied).WillReturnRows(dalitest.NewRows("version").AddRow(1).AddRow(2))
+	mock.ExpectExec("INSERT INTO `user` VALUES (1)")
+	mock.ExpectExecRegexp("^INSERT INTO `dali_migrations` ")
+	expectUnlock(mock)
+
+	m, err := New(db, migrations, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Using another connection than the one holding
+	// the lock would block until the timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLockNotAcquired(t *testing.T) {
+	db, mock := dalitest.New(dialect.MySQL)
+	mock.ExpectQuery("SELECT GET_LOCK('deploy', -1)").
+		WillReturnRows(dalitest.NewRows("lock").AddRow(nil))
+
+	m, err := New(db, migrations, &Options{LockName: "deploy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(context.Background()); err == nil || err.Error() != "migrate: lock deploy not acquired" {
+		t.Errorf("got %v, want lock not acquired", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+// transactional is a dialect with transactional DDL
+// and without advisory locks.
+type transactional struct{ dialect.Dialect }
+
+func (transactional) TransactionalDDL() bool { return true }
+
+func (transactional) SplitStatements(script string) []string {
+	return dialect.MySQL.(dialect.StatementSplitter).SplitStatements(script)
+}
+
+// plain is a dialect without optional interfaces.
+type plain struct{ dialect.Dialect }
+
+func TestDownTransactional(t *testing.T) {
+	db, mock := dalitest.New(transactional{dialect.MySQL})
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS `versions` (`version` BIGINT NOT NULL PRIMARY KEY, " +
+		"`name` VARCHAR(255) NOT NULL, `applied_at` TIMESTAMP NOT NULL)")
+	mock.ExpectQuery("SELECT `version` FROM `versions` ORDER BY `version`").
+		WillReturnRows(dalitest.NewRows("version").AddRow(1).AddRow(2))
+	mock.ExpectBegin()
+	mock.ExpectExec("ALTER TABLE `user` DROP `email`")
+	mock.ExpectExec("DELETE FROM `versions` WHERE `version` = 2")
+	mock.ExpectCommit()
+
+	m, err := New(db, migrations, &Options{Table: "versions"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Down(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	// The seed migration cannot be reverted.
+	mock.ExpectExecRegexp("^CREATE TABLE")
+	mock.ExpectQuery("SELECT `version` FROM `versions` ORDER BY `version`").
+		WillReturnRows(dalitest.NewRows("version").AddRow(10))
+	if err := m.Down(context.Background()); err == nil || err.Error() != "migrate: migration 10_seed cannot be reverted" {
+		t.Errorf("got %v, want cannot be reverted", err)
+	}
+}
+
+func TestUpTransactionalFailure(t *testing.T) {
+	db, mock := dalitest.New(transactional{dialect.MySQL})
+	mock.ExpectExec(createTable)
+	mock.ExpectQuery(selectApplied).WillReturnRows(dalitest.NewRows("version"))
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE TABLE `user` (`id` INT)").WillReturnError(errors.New("boom"))
+	mock.ExpectRollback()
+
+	m, err := New(db, migrations, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(context.Background()); err == nil || err.Error() != "migrate: 0001_create_user.up.sql: boom" {
+		t.Errorf("got %v, want boom", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	db, mock := dalitest.New(dialect.MySQL)
+	mock.ExpectQuery(selectApplied).WillReturnError(errors.New("no such table"))
+
+	var buf strings.Builder
+	m, err := New(db, migrations, &Options{DryRun: &buf})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+	want := "-- 0001_create_user.up.sql\n" +
+		"CREATE TABLE `user` (`id` INT);\n\n" +
+		"-- 0002_add_email.up.sql\n" +
+		"ALTER TABLE `user` ADD `email` TEXT;\n" +
+		"CREATE INDEX `email` ON `user` (`email`(20));\n\n" +
+		"-- 0010_seed.up.sql\n" +
+		"INSERT INTO `user` VALUES (1);\n\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
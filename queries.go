@@ -0,0 +1,198 @@
+package dali
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mibk/dali/internal/sqlfile"
+)
+
+// Queries is a set of named queries loaded from SQL files. Each query
+// is preceded by a header comment with its name:
+//
+//	-- name: FindUser
+//	SELECT * FROM [user] WHERE [id] = ?;
+//
+//	-- name: DeleteSessions
+//	DELETE FROM [session] WHERE [user_id] = ?;
+//
+// The queries are templates, same as the ones passed to (*DB).Query.
+// A trailing semicolon is removed. Comment lines following the query,
+// such as a description of the next query, are removed as well.
+type Queries struct {
+	queries map[string]string
+}
+
+// queryHeader matches the name header. Annotations following
+// the name (e.g. "-- name: FindUser :one User" used by dali-gen)
+// are ignored.
+var queryHeader = regexp.MustCompile(`^--\s*name:\s*([^\s:]\S*)?(?:\s+:.*)?\s*$`)
+
+// LoadQueries loads the named queries from the files in fsys matching
+// the patterns (see fs.Glob), typically an embed.FS:
+//
+//	//go:embed queries/*.sql
+//	var queryFiles embed.FS
+//
+//	var queries = dali.MustLoadQueries(queryFiles, "queries/*.sql")
+//
+// The query templates are checked for syntax errors, such as unknown
+// placeholders, by the same translator that translates them when they
+// are executed, and each name must be unique.
+func LoadQueries(fsys fs.FS, patterns ...string) (*Queries, error) {
+	qs := &Queries{queries: make(map[string]string)}
+	where := make(map[string]string)
+	for _, pattern := range patterns {
+		files, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, fmt.Errorf("dali: %w", err)
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("dali: pattern matches no files: %#q", pattern)
+		}
+		for _, file := range files {
+			b, err := fs.ReadFile(fsys, file)
+			if err != nil {
+				return nil, fmt.Errorf("dali: %w", err)
+			}
+			if err := qs.parse(file, string(b), where); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(qs.queries) == 0 {
+		return nil, errors.New("dali: no queries loaded")
+	}
+	return qs, nil
+}
+
+// MustLoadQueries is like LoadQueries but panics if the queries
+// cannot be loaded.
+func MustLoadQueries(fsys fs.FS, patterns ...string) *Queries {
+	qs, err := LoadQueries(fsys, patterns...)
+	if err != nil {
+		panic(err)
+	}
+	return qs
+}
+
+// parse adds the queries in src read from file. where records
+// the positions of the queries for reporting duplicates.
+func (qs *Queries) parse(file, src string, where map[string]string) error {
+	var (
+		name  string
+		line  int // of the name header
+		query strings.Builder
+	)
+	add := func() error {
+		if name == "" {
+			return nil
+		}
+		pos := fmt.Sprintf("%s:%d", file, line)
+		q := sqlfile.TrimQuery(query.String())
+		if q == "" {
+			return fmt.Errorf("dali: %s: empty query %s", pos, name)
+		}
+		if prev, ok := where[name]; ok {
+			return fmt.Errorf("dali: %s: query %s already defined at %s", pos, name, prev)
+		}
+		if e := checkTemplate(q); e != nil {
+			return fmt.Errorf("dali: %s: query %s: %s at offset %d: %w", pos, name, e.Placeholder, e.Offset, e.Err)
+		}
+		qs.queries[name] = q
+		where[name] = pos
+		return nil
+	}
+	for i, l := range strings.SplitAfter(src, "\n") {
+		m := queryHeader.FindStringSubmatch(strings.TrimRight(l, "\r\n"))
+		if m == nil {
+			if name == "" {
+				if s := strings.TrimSpace(l); s != "" && !strings.HasPrefix(s, "--") {
+					return fmt.Errorf("dali: %s:%d: query without a name header", file, i+1)
+				}
+				continue
+			}
+			query.WriteString(l)
+			continue
+		}
+		if err := add(); err != nil {
+			return err
+		}
+		if m[1] == "" {
+			return fmt.Errorf("dali: %s:%d: missing query name", file, i+1)
+		}
+		name, line = m[1], i+1
+		query.Reset()
+	}
+	return add()
+}
+
+// SQL returns the template of the named query.
+func (qs *Queries) SQL(name string) (query string, ok bool) {
+	query, ok = qs.queries[name]
+	return query, ok
+}
+
+// Names returns the sorted names of the queries.
+func (qs *Queries) Names() []string {
+	names := make([]string, 0, len(qs.queries))
+	for name := range qs.queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// On returns the queries bound to q, a DB or a Tx.
+func (qs *Queries) On(q Querier) *NamedQuerier {
+	return &NamedQuerier{qs: qs, q: q}
+}
+
+// NamedQuerier executes named queries using a Querier.
+type NamedQuerier struct {
+	qs *Queries
+	q  Querier
+}
+
+func (nq *NamedQuerier) lookup(name string) (string, error) {
+	query, ok := nq.qs.queries[name]
+	if !ok {
+		return "", fmt.Errorf("dali: unknown query %s", name)
+	}
+	return query, nil
+}
+
+// QueryWithContext is like (*DB).QueryWithContext but it uses
+// the named query.
+func (nq *NamedQuerier) QueryWithContext(ctx context.Context, name string, args ...interface{}) *Query {
+	query, err := nq.lookup(name)
+	if err != nil {
+		return &Query{ctx: ctx, err: err}
+	}
+	return nq.q.QueryWithContext(ctx, query, args...)
+}
+
+// Query is like (*DB).Query but it uses the named query.
+func (nq *NamedQuerier) Query(name string, args ...interface{}) *Query {
+	return nq.QueryWithContext(context.Background(), name, args...)
+}
+
+// PrepareContext is like (*DB).PrepareContext but it uses
+// the named query.
+func (nq *NamedQuerier) PrepareContext(ctx context.Context, name string, args ...interface{}) (*Stmt, error) {
+	query, err := nq.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	return nq.q.PrepareContext(ctx, query, args...)
+}
+
+// Prepare is like (*DB).Prepare but it uses the named query.
+func (nq *NamedQuerier) Prepare(name string, args ...interface{}) (*Stmt, error) {
+	return nq.PrepareContext(context.Background(), name, args...)
+}
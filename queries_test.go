@@ -0,0 +1,111 @@
+package dali
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadQueries(t *testing.T) {
+	fsys := fstest.MapFS{
+		"queries/user.sql": {Data: []byte(`-- Queries working with users.
+
+-- name: FindUser
+-- The user must exist.
+SELECT * FROM [user]
+WHERE [id] = ?;
+
+-- name: UserNames :all User
+SELECT ?ident... FROM [user] WHERE [id] IN (?...);
+
+-- Deletes what's [left]?
+-- name: DeleteUser
+DELETE FROM [user] WHERE [id] = ?
+`)},
+		"queries/session.sql": {Data: []byte("-- name: DeleteSessions\r\nDELETE FROM [session] WHERE [user_id] = ?\r\n")},
+	}
+	qs, err := LoadQueries(fsys, "queries/*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := qs.Names(), []string{"DeleteSessions", "DeleteUser", "FindUser", "UserNames"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got names %q, want %q", got, want)
+	}
+	if q, _ := qs.SQL("FindUser"); q != "-- The user must exist.\nSELECT * FROM [user]\nWHERE [id] = ?" {
+		t.Errorf("unexpected FindUser: %q", q)
+	}
+
+	nq := qs.On(NewDB(db.DB, dvr))
+	tests := []struct {
+		name string
+		args []interface{}
+		want string
+	}{
+		{"FindUser", []interface{}{1}, "-- The user must exist.\nSELECT * FROM {user}\nWHERE {id} = 1"},
+		{"UserNames", []interface{}{[]string{"first", "last"}, []int{1, 2}},
+			"SELECT {first}, {last} FROM {user} WHERE {id} IN (1, 2)"},
+		{"DeleteSessions", []interface{}{3}, "DELETE FROM {session} WHERE {user_id} = 3"},
+		{"DeleteUser", []interface{}{4}, "DELETE FROM {user} WHERE {id} = 4"},
+	}
+	for _, tt := range tests {
+		if got := nq.Query(tt.name, tt.args...).String(); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := nq.Query("Missing").Exec(); err == nil || err.Error() != "dali: unknown query Missing" {
+		t.Errorf("got %v, want unknown query", err)
+	}
+	if _, err := nq.Prepare("Missing"); err == nil {
+		t.Error("prepared unknown query")
+	}
+}
+
+func TestLoadQueriesErrors(t *testing.T) {
+	tests := []struct {
+		files map[string]string
+		err   string
+	}{
+		{map[string]string{"a.sql": "SELECT 1"},
+			"dali: a.sql:1: query without a name header"},
+		{map[string]string{"a.sql": "-- name:\nSELECT 1"},
+			"dali: a.sql:1: missing query name"},
+		{map[string]string{"a.sql": "-- name: :one User\nSELECT 1"},
+			"dali: a.sql:1: missing query name"},
+		{map[string]string{"a.sql": "-- name: A\n;\n-- name: B\nSELECT 1"},
+			"dali: a.sql:1: empty query A"},
+		{map[string]string{"a.sql": "-- name: A\nSELECT 1", "b.sql": "\n-- name: A\nSELECT 2"},
+			"dali: b.sql:2: query A already defined at a.sql:1"},
+		{map[string]string{"a.sql": "-- name: A\nSELECT ?foo"},
+			"dali: a.sql:1: query A: ?foo at offset 7: unknown placeholder ?foo"},
+		{map[string]string{"a.sql": "-- name: A\nUPDATE [t] ?set... WHERE [id] = ?"},
+			"dali: a.sql:1: query A: ?set... at offset 11: ?set cannot be expanded (...) or doesn't exist"},
+		{map[string]string{"a.sql": "-- name: A\nSELECT [id FROM user"},
+			"dali: a.sql:1: query A: [ at offset 7: identifier not terminated"},
+		{map[string]string{"a.sql": "-- only a comment"},
+			"dali: no queries loaded"},
+		{map[string]string{"a.txt": ""},
+			"dali: pattern matches no files: `*.sql`"},
+	}
+	for _, tt := range tests {
+		fsys := make(fstest.MapFS)
+		for name, data := range tt.files {
+			fsys[name] = &fstest.MapFile{Data: []byte(data)}
+		}
+		_, err := LoadQueries(fsys, "*.sql")
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("%v: got %v, want %s", tt.files, err, tt.err)
+		}
+	}
+}
+
+func TestMustLoadQueries(t *testing.T) {
+	defer func() {
+		err, _ := recover().(error)
+		if err == nil || !strings.Contains(err.Error(), "no files") {
+			t.Errorf("got %v, want panic", err)
+		}
+	}()
+	MustLoadQueries(fstest.MapFS{}, "*.sql")
+}
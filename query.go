@@ -14,6 +14,24 @@ type Query struct {
 	query  string
 	args   []interface{}
 	err    error
+	event  QueryEvent
+}
+
+// context returns the query context carrying the QueryEvent
+// for op.
+func (q *Query) context(op Op) context.Context {
+	ev := q.event
+	ev.Op = op
+	return contextWithEvent(q.ctx, &ev)
+}
+
+// Idempotent marks the query as safe to be executed more than once.
+// This allows middlewares to retry Exec (see the daliretry package);
+// queries returning rows are considered idempotent anyway.
+// It returns q.
+func (q *Query) Idempotent() *Query {
+	q.event.Idempotent = true
+	return q
 }
 
 // Exec executes the query that shouldn't return rows.
@@ -22,15 +40,19 @@ func (q *Query) Exec() (sql.Result, error) {
 	if q.err != nil {
 		return nil, q.err
 	}
-	return q.execer.ExecContext(q.ctx, q.query, q.args...)
+	return q.execer.ExecContext(q.context(OpExec), q.query, q.args...)
 }
 
 // Rows executes that query that should return rows, typically a SELECT.
 func (q *Query) Rows() (*sql.Rows, error) {
+	return q.rows(OpRows)
+}
+
+func (q *Query) rows(op Op) (*sql.Rows, error) {
 	if q.err != nil {
 		return nil, q.err
 	}
-	return q.execer.QueryContext(q.ctx, q.query, q.args...)
+	return q.execer.QueryContext(q.context(op), q.query, q.args...)
 }
 
 // ScanRow executes the query that is expected to return at most one row.
@@ -42,7 +64,7 @@ func (q *Query) ScanRow(dest ...interface{}) error {
 	if q.err != nil {
 		return q.err
 	}
-	return q.execer.QueryRowContext(q.ctx, q.query, q.args...).Scan(dest...)
+	return q.execer.QueryRowContext(q.context(OpScanRow), q.query, q.args...).Scan(dest...)
 }
 
 func (q *Query) String() string {
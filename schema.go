@@ -0,0 +1,100 @@
+package dali
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/mibk/dali/dialect"
+)
+
+// Table describes a database table.
+type Table struct {
+	Name string `db:"name"`
+	View bool   `db:"view"`
+}
+
+// Column describes a table column.
+type Column struct {
+	Table         string `db:"table"`
+	Name          string `db:"name"`
+	DataType      string `db:"data_type"`   // e.g. "int"
+	ColumnType    string `db:"column_type"` // e.g. "int(10) unsigned"
+	Nullable      bool   `db:"nullable"`
+	PrimaryKey    bool   `db:"primary_key"`
+	AutoIncrement bool   `db:"auto_increment"`
+}
+
+// Index describes a table index.
+type Index struct {
+	Name    string
+	Unique  bool
+	Columns []string
+}
+
+func (db *DB) schemaQuerier() (dialect.SchemaQuerier, error) {
+	sq, ok := db.dialect.(dialect.SchemaQuerier)
+	if !ok {
+		return nil, fmt.Errorf("dali: dialect %T cannot describe the schema", db.dialect)
+	}
+	return sq, nil
+}
+
+// Tables returns the tables, including views, in the current database
+// ordered by name. The dialect must implement dialect.SchemaQuerier.
+func (db *DB) Tables(ctx context.Context) ([]Table, error) {
+	sq, err := db.schemaQuerier()
+	if err != nil {
+		return nil, err
+	}
+	var tables []Table
+	if err := db.QueryWithContext(ctx, sq.TablesQuery()).All(&tables); err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
+
+// Columns returns the columns of the tables ordered by the table name
+// and the column position. At least one table must be given; the columns
+// of all the tables are loaded by a single query. The dialect must
+// implement dialect.SchemaQuerier.
+func (db *DB) Columns(ctx context.Context, tables ...string) ([]Column, error) {
+	sq, err := db.schemaQuerier()
+	if err != nil {
+		return nil, err
+	}
+	if len(tables) == 0 {
+		return nil, errors.New("dali: no tables to load the columns of")
+	}
+	var cols []Column
+	if err := db.QueryWithContext(ctx, sq.ColumnsQuery(), tables).All(&cols); err != nil {
+		return nil, err
+	}
+	return cols, nil
+}
+
+// Indexes returns the indexes of table, including the primary key,
+// ordered by name. The dialect must implement dialect.SchemaQuerier.
+func (db *DB) Indexes(ctx context.Context, table string) ([]Index, error) {
+	sq, err := db.schemaQuerier()
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		Name   string `db:"name"`
+		Unique bool   `db:"unique"`
+		Column string `db:"column"`
+	}
+	if err := db.QueryWithContext(ctx, sq.IndexesQuery(), table).All(&rows); err != nil {
+		return nil, err
+	}
+	var indexes []Index
+	for _, r := range rows {
+		if n := len(indexes); n > 0 && indexes[n-1].Name == r.Name {
+			indexes[n-1].Columns = append(indexes[n-1].Columns, r.Column)
+			continue
+		}
+		indexes = append(indexes, Index{Name: r.Name, Unique: r.Unique, Columns: []string{r.Column}})
+	}
+	return indexes, nil
+}
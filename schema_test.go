@@ -0,0 +1,82 @@
+package dali
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mibk/dali/dialect"
+	"github.com/mibk/dali/internal/testdriver"
+)
+
+func TestSchema(t *testing.T) {
+	ctx := context.Background()
+	drv, sqldb := testdriver.New()
+	db := NewDB(sqldb, dialect.MySQL)
+
+	drv.SetRows([]string{"name", "view"},
+		[]driver.Value{"user", int64(0)},
+		[]driver.Value{"user_view", int64(1)},
+	)
+	tables, err := db.Tables(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Table{{"user", false}, {"user_view", true}}; !reflect.DeepEqual(tables, want) {
+		t.Errorf("got tables %v, want %v", tables, want)
+	}
+
+	drv.SetRows([]string{"table", "name", "data_type", "column_type", "nullable", "primary_key", "auto_increment"},
+		[]driver.Value{"group", "id", "int", "int(11)", int64(0), int64(1), int64(1)},
+		[]driver.Value{"user", "id", "bigint", "bigint(20) unsigned", int64(0), int64(1), int64(1)},
+		[]driver.Value{"user", "email", "varchar", "varchar(100)", []byte("1"), []byte("0"), []byte("0")},
+	)
+	cols, err := db.Columns(ctx, "user", "group")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantCols := []Column{
+		{"group", "id", "int", "int(11)", false, true, true},
+		{"user", "id", "bigint", "bigint(20) unsigned", false, true, true},
+		{"user", "email", "varchar", "varchar(100)", true, false, false},
+	}
+	if !reflect.DeepEqual(cols, wantCols) {
+		t.Errorf("got columns %v, want %v", cols, wantCols)
+	}
+
+	drv.SetRows([]string{"name", "unique", "column"},
+		[]driver.Value{"PRIMARY", int64(1), "id"},
+		[]driver.Value{"group_created", int64(0), "group_id"},
+		[]driver.Value{"group_created", int64(0), "created"},
+		[]driver.Value{"user_email", int64(1), "email"},
+	)
+	indexes, err := db.Indexes(ctx, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantIndexes := []Index{
+		{"PRIMARY", true, []string{"id"}},
+		{"group_created", false, []string{"group_id", "created"}},
+		{"user_email", true, []string{"email"}},
+	}
+	if !reflect.DeepEqual(indexes, wantIndexes) {
+		t.Errorf("got indexes %v, want %v", indexes, wantIndexes)
+	}
+
+	queries := drv.Queries()
+	if len(queries) != 3 ||
+		!strings.Contains(queries[1], "`TABLE_NAME` IN ('user', 'group')") ||
+		!strings.Contains(queries[2], "`TABLE_NAME` = 'user'") {
+		t.Errorf("unexpected queries %q", queries)
+	}
+	if _, err := db.Columns(ctx); err == nil {
+		t.Errorf("no tables: an error was expected but none given")
+	}
+
+	if _, err := NewDB(sqldb, dvr).Tables(ctx); err == nil ||
+		err.Error() != "dali: dialect *dali.FakeDialect cannot describe the schema" {
+		t.Errorf("got %v, want cannot describe the schema", err)
+	}
+}
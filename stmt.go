@@ -9,7 +9,8 @@ import (
 type Stmt struct {
 	stmt       *sql.Stmt
 	sql        string
-	middleware func(Execer) Execer
+	middleware Middleware
+	event      QueryEvent
 }
 
 // BindContext binds args to the prepared statement and returns a Query struct
@@ -20,6 +21,7 @@ func (s *Stmt) BindContext(ctx context.Context, args ...interface{}) *Query {
 		execer: s.middleware(stmtExecer{s.stmt}),
 		query:  s.sql,
 		args:   args,
+		event:  s.event,
 	}
 }
 
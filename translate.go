@@ -3,6 +3,7 @@ package dali
 import (
 	"bytes"
 	"database/sql/driver"
+	"errors"
 	"fmt"
 	"reflect"
 	"sort"
@@ -12,6 +13,7 @@ import (
 	"unicode/utf8"
 
 	"github.com/mibk/dali/dialect"
+	"github.com/mibk/dali/internal/placeholder"
 )
 
 // Marshaler is the interface implemented by types that can marshal
@@ -25,6 +27,7 @@ type Marshaler interface {
 type Translator struct {
 	dialect      dialect.Dialect
 	preparedStmt bool
+	check        bool // only check the template, ignoring args
 
 	err  error
 	args []interface{}
@@ -49,13 +52,92 @@ func translatePreparedStmt(d dialect.Dialect, sql string, args []interface{}) (s
 
 // Translate processes sql and args using the dialect specified in t.
 // It returns the resulting SQL query and an error, if there is one.
+// The error is of type *TranslateError.
 func (t Translator) Translate(sql string, args []interface{}) (string, error) {
 	t.args = args
-	s, err := t.translate(sql)
-	if err != nil {
-		return "", fmt.Errorf("dali: %v", err)
+	return t.translate(sql)
+}
+
+// checkTemplate reports the errors of the query template sql that
+// don't depend on the args, such as unknown placeholders.
+func checkTemplate(sql string) *TranslateError {
+	t := Translator{check: true}
+	if _, err := t.translate(sql); err != nil {
+		return err.(*TranslateError)
+	}
+	return nil
+}
+
+// Errors wrapped by TranslateError when the number of args doesn't match
+// the number of placeholders.
+var (
+	ErrNotEnoughArgs = errors.New("there is not enough args for placeholders")
+	ErrTooManyArgs   = errors.New("too many args")
+)
+
+// TranslateError describes a failure to translate a query.
+type TranslateError struct {
+	// Placeholder is the placeholder that failed, e.g. "?ident..."
+	// or "[" for an identifier. It is empty if there were too many args.
+	Placeholder string
+
+	// Offset is the byte offset of the placeholder in the query
+	// (or the length of the query if there were too many args).
+	Offset int
+
+	// ArgIndex is the index of the arg consumed by the placeholder
+	// (or of the first superfluous arg), or -1 if there is none.
+	// ArgType is the type of the arg, or nil if the arg is missing.
+	ArgIndex int
+	ArgType  reflect.Type
+
+	Err error
+}
+
+// Error describes the failure, e.g.
+//
+//	dali: ?ident at offset 17 (arg 2, got int): ?ident expects the argument to be a string
+func (e *TranslateError) Error() string {
+	if e.Placeholder == "" {
+		// Superfluous arg.
+		if e.ArgType == nil {
+			return fmt.Sprintf("dali: arg %d: %v", e.ArgIndex, e.Err)
+		}
+		return fmt.Sprintf("dali: arg %d (got %v): %v", e.ArgIndex, e.ArgType, e.Err)
+	}
+	switch {
+	case e.ArgIndex < 0:
+		return fmt.Sprintf("dali: %s at offset %d: %v", e.Placeholder, e.Offset, e.Err)
+	case e.ArgType == nil:
+		return fmt.Sprintf("dali: %s at offset %d (arg %d): %v", e.Placeholder, e.Offset, e.ArgIndex, e.Err)
 	}
-	return s, nil
+	return fmt.Sprintf("dali: %s at offset %d (arg %d, got %v): %v",
+		e.Placeholder, e.Offset, e.ArgIndex, e.ArgType, e.Err)
+}
+
+func (e *TranslateError) Unwrap() error { return e.Err }
+
+type tooManyArgsError struct {
+	expected int
+}
+
+func (e tooManyArgsError) Error() string {
+	return fmt.Sprintf("only %d args are expected", e.expected)
+}
+
+func (e tooManyArgsError) Is(target error) bool { return target == ErrTooManyArgs }
+
+func (p *Translator) errorAt(placeholder string, offset, argIndex int, err error) error {
+	e := &TranslateError{
+		Placeholder: placeholder,
+		Offset:      offset,
+		ArgIndex:    argIndex,
+		Err:         err,
+	}
+	if argIndex >= 0 && argIndex < len(p.args) {
+		e.ArgType = reflect.TypeOf(p.args[argIndex])
+	}
+	return e
 }
 
 func (t Translator) clone() Translator {
@@ -83,42 +165,41 @@ func (p *Translator) translate(sql string) (string, error) {
 		case '[':
 			w := strings.IndexRune(sql[pos:], ']')
 			if w == -1 {
-				return "", fmt.Errorf("identifier not terminated")
+				return "", p.errorAt("[", pos-1, -1, placeholder.ErrUnterminatedIdent)
+			}
+			if !p.check {
+				col := sql[pos : pos+w]
+				p.dialect.EscapeIdent(b, col)
 			}
-			col := sql[pos : pos+w]
-			p.dialect.EscapeIdent(b, col)
 			pos += w + 1 // size of ']'
 		case '?':
-			start, end := pos, pos
-			var expand bool
-			for {
-				r, w := utf8.DecodeRuneInString(sql[pos:])
-				if r < 'a' || r > 'z' {
-					if strings.HasPrefix(sql[pos:], "...") {
-						pos += 3
-						expand = true
-					}
-					break
-				}
-				pos += w
-				end = pos
+			offset := pos - 1
+			typ, expand, next := placeholder.Scan(sql, pos)
+			pos = next
+			index := p.index
+			if p.check {
+				index = -1
 			}
-			if err := p.interpolate(b, sql[start:end], expand); err != nil {
-				return "", err
+			err := placeholder.Check(typ, expand)
+			if err == nil && !p.check {
+				err = p.interpolate(b, typ, expand)
+			}
+			if err != nil {
+				return "", p.errorAt(sql[offset:pos], offset, index, err)
 			}
 		default:
 			b.WriteRune(r)
 		}
 	}
 	if p.index < len(p.args) {
-		return "", fmt.Errorf("only %d args are expected", p.index)
+		return "", p.errorAt("", len(sql), p.index, tooManyArgsError{p.index})
 	}
 	return b.String(), nil
 }
 
 func (p *Translator) nextArg() interface{} {
 	if p.index >= len(p.args) {
-		p.try(fmt.Errorf("there is not enough args for placeholders"))
+		p.try(ErrNotEnoughArgs)
 		return nil
 	}
 	v := p.args[p.index]
@@ -131,6 +212,8 @@ func (p *Translator) nextParamNumber() int {
 	return p.param
 }
 
+// interpolate interpolates the placeholder of type typ, which must
+// have been checked by placeholder.Check.
 func (p *Translator) interpolate(b *bytes.Buffer, typ string, expand bool) error {
 	if expand {
 		switch typ {
@@ -153,8 +236,6 @@ func (p *Translator) interpolate(b *bytes.Buffer, typ string, expand bool) error
 		case "values":
 			p.try(p.checkInterpolationOf("?values..."))
 			p.try(p.printMultiValuesClause(b, p.nextArg()))
-		default:
-			return fmt.Errorf("?%s cannot be expanded (...) or doesn't exist", typ)
 		}
 	} else {
 		switch typ {
@@ -182,7 +263,7 @@ func (p *Translator) interpolate(b *bytes.Buffer, typ string, expand bool) error
 			case Marshaler:
 				sql, err := arg.MarshalSQL(p.clone())
 				if err != nil {
-					return fmt.Errorf("marshal SQL: %v", err)
+					return fmt.Errorf("marshal SQL: %w", err)
 				}
 				b.WriteString(sql)
 			case string:
@@ -190,8 +271,6 @@ func (p *Translator) interpolate(b *bytes.Buffer, typ string, expand bool) error
 			default:
 				return fmt.Errorf("?sql expects the argument to be a string or Marshaler")
 			}
-		default:
-			return fmt.Errorf("unknown placeholder ?%s", typ)
 		}
 	}
 	return p.err
@@ -3,8 +3,10 @@ package dali
 import (
 	"database/sql"
 	"database/sql/driver"
+	"errors"
 	"fmt"
 	"io"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -65,7 +67,7 @@ var placeholderTests = []struct {
 		"({Event}, {Started}, {Finished}) VALUES ('Waking up', " +
 			"'2015-04-05 06:07:08 +0000 UTC', NULL)"},
 	{"?values", Args{SpecialStruct{"Waking up", parseTime("2015-04-05 06:07:08"),
-		sql.NullString{"2015-04-05 06:38:15", true}}},
+		sql.NullString{String: "2015-04-05 06:38:15", Valid: true}}},
 		"({Event}, {Started}, {Finished}) VALUES ('Waking up', " +
 			"'2015-04-05 06:07:08 +0000 UTC', '2015-04-05 06:38:15')"},
 
@@ -202,36 +204,52 @@ var errorTests = []struct {
 	args []interface{}
 	err  string
 }{
-	{"SELECT [user FROM", Args{}, "dali: identifier not terminated"},
-	{"INSERT INTO ?ident", Args{}, "dali: there is not enough args for placeholders"},
-	{"SELECT ?, ?", Args{3, 4, 5}, "dali: only 2 args are expected"},
-	{"INSERT INTO ?ident", Args{5}, "dali: ?ident expects the argument to be a string"},
-	{"INSERT INTO ?u", Args{5}, "dali: unknown placeholder ?u"},
-	{"INSERT ?u...", Args{nil}, "dali: ?u cannot be expanded (...) or doesn't exist"},
-	{"INSERT INTO ?", Args{func() {}}, "dali: invalid argument type: func()"},
-	{"WHERE IN ?...", Args{14}, "dali: ?... expects the argument to be a slice"},
-	{"INSERT ?values", Args{ptrPtrUser()}, "dali: argument must be a pointer to a struct"},
+	{"SELECT [user FROM", Args{}, "dali: [ at offset 7: identifier not terminated"},
+	{"INSERT INTO ?ident", Args{},
+		"dali: ?ident at offset 12 (arg 0): there is not enough args for placeholders"},
+	{"SELECT ?, ?", Args{3, 4, 5}, "dali: arg 2 (got int): only 2 args are expected"},
+	{"INSERT INTO ?ident", Args{5},
+		"dali: ?ident at offset 12 (arg 0, got int): ?ident expects the argument to be a string"},
+	{"INSERT INTO ?u", Args{5}, "dali: ?u at offset 12 (arg 0, got int): unknown placeholder ?u"},
+	{"INSERT ?u...", Args{nil},
+		"dali: ?u... at offset 7 (arg 0): ?u cannot be expanded (...) or doesn't exist"},
+	{"INSERT INTO ?", Args{func() {}},
+		"dali: ? at offset 12 (arg 0, got func()): invalid argument type: func()"},
+	{"WHERE IN ?...", Args{14},
+		"dali: ?... at offset 9 (arg 0, got int): ?... expects the argument to be a slice"},
+	{"INSERT ?values", Args{ptrPtrUser()},
+		"dali: ?values at offset 7 (arg 0, got **dali.User): argument must be a pointer to a struct"},
 	{"INSERT ?values...", Args{[]**User{}},
-		"dali: ?values... expects the argument to be a slice of structs"},
+		"dali: ?values... at offset 7 (arg 0, got []**dali.User): ?values... expects the argument to be a slice of structs"},
 
 	// empty slice
-	{"SELECT ?ident...", Args{[]int{}}, "dali: ?ident... expects the argument to be a []string"},
-	{"SELECT ?ident...", Args{[]string{}}, "dali: empty slice passed to ?ident..."},
+	{"SELECT ?ident...", Args{[]int{}},
+		"dali: ?ident... at offset 7 (arg 0, got []int): ?ident... expects the argument to be a []string"},
+	{"SELECT ?ident...", Args{[]string{}},
+		"dali: ?ident... at offset 7 (arg 0, got []string): empty slice passed to ?ident..."},
 	{"INSERT ?values...", Args{[]string{}},
-		"dali: ?values... expects the argument to be a slice of structs"},
-	{"INSERT ?values...", Args{[]User{}}, "dali: empty slice passed to ?values..."},
+		"dali: ?values... at offset 7 (arg 0, got []string): ?values... expects the argument to be a slice of structs"},
+	{"INSERT ?values...", Args{[]User{}},
+		"dali: ?values... at offset 7 (arg 0, got []dali.User): empty slice passed to ?values..."},
 
 	// empty columns
-	{"INSERT ?values", Args{Map{}}, "dali: no columns derived from dali.Map"},
-	{"INSERT ?values", Args{struct{}{}}, "dali: no columns derived from struct {}"},
-	{"INSERT ?values...", Args{[]struct{}{{}}}, "dali: no columns derived from []struct {}"},
-	{"INSERT ?values", Args{OmitEverything{}}, "dali: no columns derived from dali.OmitEverything"},
-	{"INSERT ?set", Args{struct{}{}}, "dali: no columns derived from struct {}"},
+	{"INSERT ?values", Args{Map{}},
+		"dali: ?values at offset 7 (arg 0, got dali.Map): no columns derived from dali.Map"},
+	{"INSERT ?values", Args{struct{}{}},
+		"dali: ?values at offset 7 (arg 0, got struct {}): no columns derived from struct {}"},
+	{"INSERT ?values...", Args{[]struct{}{{}}},
+		"dali: ?values... at offset 7 (arg 0, got []struct {}): no columns derived from []struct {}"},
+	{"INSERT ?values", Args{OmitEverything{}},
+		"dali: ?values at offset 7 (arg 0, got dali.OmitEverything): no columns derived from dali.OmitEverything"},
+	{"INSERT ?set", Args{struct{}{}},
+		"dali: ?set at offset 7 (arg 0, got struct {}): no columns derived from struct {}"},
 
 	// ?sql
-	{"INSERT INTO ?sql", Args{5}, "dali: ?sql expects the argument to be a string or Marshaler"},
+	{"INSERT INTO ?sql", Args{5},
+		"dali: ?sql at offset 12 (arg 0, got int): ?sql expects the argument to be a string or Marshaler"},
 	{"SELECT WHERE ?sql", Args{new(Where).And("?")},
-		"dali: marshal SQL: dali: there is not enough args for placeholders"},
+		"dali: ?sql at offset 13 (arg 0, got *dali.Where): marshal SQL: " +
+			"dali: ? at offset 1 (arg 0): there is not enough args for placeholders"},
 }
 
 func TestErrors(t *testing.T) {
@@ -248,6 +266,61 @@ func TestErrors(t *testing.T) {
 	}
 }
 
+func TestTranslateError(t *testing.T) {
+	tests := []struct {
+		sql  string
+		args []interface{}
+		want TranslateError
+		is   error
+		msg  string
+	}{
+		{"SELECT [a], [b FROM x", Args{},
+			TranslateError{Placeholder: "[", Offset: 12, ArgIndex: -1}, nil,
+			"dali: [ at offset 12: identifier not terminated"},
+		{"SELECT ?, ?\nFROM ?ident", Args{1, 2},
+			TranslateError{Placeholder: "?ident", Offset: 17, ArgIndex: 2}, ErrNotEnoughArgs,
+			"dali: ?ident at offset 17 (arg 2): there is not enough args for placeholders"},
+		{"SELECT ?", Args{1, "two"},
+			TranslateError{Offset: 8, ArgIndex: 1, ArgType: reflect.TypeOf("")}, ErrTooManyArgs,
+			"dali: arg 1 (got string): only 1 args are expected"},
+		{"SELECT ?", Args{1, nil},
+			TranslateError{Offset: 8, ArgIndex: 1}, ErrTooManyArgs,
+			"dali: arg 1: only 1 args are expected"},
+		{"SELECT ?ident... FROM ?ident", Args{[]string{"a"}, 5},
+			TranslateError{Placeholder: "?ident", Offset: 22, ArgIndex: 1, ArgType: reflect.TypeOf(0)}, nil,
+			"dali: ?ident at offset 22 (arg 1, got int): ?ident expects the argument to be a string"},
+		{"WHERE [id] IN (?...)", Args{14},
+			TranslateError{Placeholder: "?...", Offset: 15, ArgIndex: 0, ArgType: reflect.TypeOf(0)}, nil,
+			"dali: ?... at offset 15 (arg 0, got int): ?... expects the argument to be a slice"},
+		{"WHERE ?sql", Args{new(Where).And("?")},
+			TranslateError{Placeholder: "?sql", Offset: 6, ArgIndex: 0, ArgType: reflect.TypeOf(new(Where))},
+			ErrNotEnoughArgs,
+			"dali: ?sql at offset 6 (arg 0, got *dali.Where): marshal SQL: " +
+				"dali: ? at offset 1 (arg 0): there is not enough args for placeholders"},
+	}
+	tr := Translator{dialect: FakeDialect{}}
+	for _, tt := range tests {
+		_, err := tr.Translate(tt.sql, tt.args)
+		var got *TranslateError
+		if !errors.As(err, &got) {
+			t.Errorf("%s: got %v, want *TranslateError", tt.sql, err)
+			continue
+		}
+		if got.Placeholder != tt.want.Placeholder || got.Offset != tt.want.Offset ||
+			got.ArgIndex != tt.want.ArgIndex || got.ArgType != tt.want.ArgType {
+			t.Errorf("%s:\n got: %q at %d, arg %d (%v)\nwant: %q at %d, arg %d (%v)", tt.sql,
+				got.Placeholder, got.Offset, got.ArgIndex, got.ArgType,
+				tt.want.Placeholder, tt.want.Offset, tt.want.ArgIndex, tt.want.ArgType)
+		}
+		if tt.is != nil && !errors.Is(err, tt.is) {
+			t.Errorf("%s: %v is not %v", tt.sql, err, tt.is)
+		}
+		if msg := err.Error(); msg != tt.msg {
+			t.Errorf("%s:\n got: %s\nwant: %s", tt.sql, msg, tt.msg)
+		}
+	}
+}
+
 const sqlTimeFmt = "2006-01-02 15:04:05"
 
 func parseTime(s string) time.Time {
@@ -311,18 +384,24 @@ var preparedStmtTests = []struct {
 	{"WHERE [name] LIKE ? AND [age] < ?", Args{}, "WHERE {name} LIKE &1 AND {age} < &2", ""},
 
 	// arg count mismatch
-	{"SELECT ?ident", Args{}, "", "dali: there is not enough args for placeholders"},
-	{"SELECT ?ident", Args{"name", 3}, "", "dali: only 1 args are expected"},
+	{"SELECT ?ident", Args{}, "",
+		"dali: ?ident at offset 7 (arg 0): there is not enough args for placeholders"},
+	{"SELECT ?ident", Args{"name", 3}, "", "dali: arg 1 (got int): only 1 args are expected"},
 
 	// unsupported placeholders
-	{"SELECT ?...", Args{}, "", "dali: ?... cannot be used in prepared statements"},
-	{"INSERT ?values", Args{}, "", "dali: ?values cannot be used in prepared statements"},
-	{"INSERT ?values...", Args{}, "", "dali: ?values... cannot be used in prepared statements"},
-	{"INSERT ?set", Args{}, "", "dali: ?set cannot be used in prepared statements"},
+	{"SELECT ?...", Args{}, "",
+		"dali: ?... at offset 7 (arg 0): ?... cannot be used in prepared statements"},
+	{"INSERT ?values", Args{}, "",
+		"dali: ?values at offset 7 (arg 0): ?values cannot be used in prepared statements"},
+	{"INSERT ?values...", Args{}, "",
+		"dali: ?values... at offset 7 (arg 0): ?values... cannot be used in prepared statements"},
+	{"INSERT ?set", Args{}, "",
+		"dali: ?set at offset 7 (arg 0): ?set cannot be used in prepared statements"},
 
 	// ?sql
 	{"SELECT WHERE ?sql", Args{new(Where).And("x IN (?...)", []int{2, 3})},
-		"", "dali: marshal SQL: dali: ?... cannot be used in prepared statements"},
+		"", "dali: ?sql at offset 13 (arg 0, got *dali.Where): marshal SQL: " +
+			"dali: ?... at offset 7 (arg 0, got []int): ?... cannot be used in prepared statements"},
 }
 
 func TestPreparedStmts(t *testing.T) {
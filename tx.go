@@ -3,6 +3,7 @@ package dali
 import (
 	"context"
 	"database/sql"
+	"sync"
 
 	"github.com/mibk/dali/dialect"
 )
@@ -12,8 +13,36 @@ import (
 // the database.
 type Tx struct {
 	Tx         *sql.Tx
+	ctx        context.Context // of BeginTx
 	dialect    dialect.Dialect
-	middleware func(Execer) Execer
+	middleware Middleware
+	hooks      []Hook
+
+	mu         sync.Mutex
+	onCommit   []func()
+	onRollback []func()
+	ending     bool        // Commit or Rollback was called
+	stop       func() bool // stops watching ctx
+}
+
+// beginTx starts a transaction using begin, calling the hooks.
+func beginTx(ctx context.Context, opts *sql.TxOptions, d dialect.Dialect, mw Middleware, hooks []Hook,
+	begin func(context.Context, *sql.TxOptions) (*sql.Tx, error)) (*Tx, error) {
+	tx := &Tx{
+		ctx:        ctx,
+		dialect:    d,
+		middleware: mw,
+		hooks:      append([]Hook(nil), hooks...),
+	}
+	done := callHooks(ctx, tx.hooks, OpBegin, QueryEvent{Tx: tx, Dialect: d})
+	var err error
+	tx.Tx, err = begin(ctx, opts)
+	done(err)
+	if err != nil {
+		return nil, err
+	}
+	tx.stop = context.AfterFunc(ctx, tx.autoRollback)
+	return tx, nil
 }
 
 // QueryWithContext is a (*DB).Query equivalent for transactions.
@@ -24,6 +53,13 @@ func (tx *Tx) QueryWithContext(ctx context.Context, query string, args ...interf
 		execer: tx.middleware(tx.Tx),
 		query:  sql,
 		err:    err,
+		event: QueryEvent{
+			Template: query,
+			Args:     args,
+			SQL:      sql,
+			Tx:       tx,
+			Dialect:  tx.dialect,
+		},
 	}
 }
 
@@ -48,11 +84,26 @@ func (tx *Tx) PrepareContext(ctx context.Context, query string, args ...interfac
 	if err != nil {
 		return nil, err
 	}
+	ev := QueryEvent{
+		Template: query,
+		Args:     args,
+		SQL:      sql,
+		Prepared: true,
+		Tx:       tx,
+		Dialect:  tx.dialect,
+	}
+	done := callHooks(ctx, tx.hooks, OpPrepare, ev)
 	stmt, err := tx.Tx.PrepareContext(ctx, sql)
+	done(err)
 	if err != nil {
 		return nil, err
 	}
-	return &Stmt{stmt, sql, tx.middleware}, nil
+	return &Stmt{
+		stmt:       stmt,
+		sql:        sql,
+		middleware: tx.middleware,
+		event:      ev,
+	}, nil
 }
 
 // Prepare creates a prepared statement for later queries or executions.
@@ -80,6 +131,7 @@ func (tx *Tx) StmtContext(ctx context.Context, stmt *Stmt) *Stmt {
 	stmt2 := new(Stmt)
 	*stmt2 = *stmt
 	stmt2.stmt = tx.Tx.StmtContext(ctx, stmt2.stmt)
+	stmt2.event.Tx = tx
 	return stmt2
 }
 
@@ -90,8 +142,103 @@ func (tx *Tx) Stmt(stmt *Stmt) *Stmt {
 	return tx.StmtContext(context.Background(), stmt)
 }
 
-// Commit commits the transaction.
-func (tx *Tx) Commit() error { return tx.Tx.Commit() }
+type txKey struct{}
 
-// Rollback aborts the transaction.
-func (tx *Tx) Rollback() error { return tx.Tx.Rollback() }
+// WithTx returns a copy of ctx carrying tx. The QueryWithContext and
+// PrepareContext methods of DB route queries to the transaction
+// carried by their context, so functions deep in the call stack can
+// participate in a transaction without having it passed explicitly.
+func WithTx(ctx context.Context, tx *Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFromContext returns the transaction stored in ctx by WithTx,
+// if any.
+func TxFromContext(ctx context.Context) (*Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(*Tx)
+	return tx, ok && tx != nil
+}
+
+// OnCommit registers f to be called after the transaction has been
+// successfully committed. Functions are called in the order in which
+// they were registered.
+func (tx *Tx) OnCommit(f func()) {
+	tx.mu.Lock()
+	tx.onCommit = append(tx.onCommit, f)
+	tx.mu.Unlock()
+}
+
+// OnRollback registers f to be called after the transaction has been
+// rolled back: by Rollback, by a failed Commit, or automatically
+// when the context passed to BeginTx is done. Functions are called
+// in the order in which they were registered, exactly once.
+func (tx *Tx) OnRollback(f func()) {
+	tx.mu.Lock()
+	tx.onRollback = append(tx.onRollback, f)
+	tx.mu.Unlock()
+}
+
+// Commit commits the transaction. If it succeeds, the functions
+// registered by OnCommit are called; otherwise, the transaction
+// has been rolled back and the functions registered by OnRollback
+// are called.
+func (tx *Tx) Commit() error {
+	tx.end()
+	done := callHooks(tx.ctx, tx.hooks, OpCommit, QueryEvent{Tx: tx, Dialect: tx.dialect})
+	err := tx.Tx.Commit()
+	done(err)
+	tx.runCallbacks(err == nil)
+	return err
+}
+
+// Rollback aborts the transaction. The functions registered by
+// OnRollback are called, unless they have already been called.
+func (tx *Tx) Rollback() error {
+	tx.end()
+	done := callHooks(tx.ctx, tx.hooks, OpRollback, QueryEvent{Tx: tx, Dialect: tx.dialect})
+	err := tx.Tx.Rollback()
+	done(err)
+	tx.runCallbacks(false)
+	return err
+}
+
+// end marks the transaction as ending by Commit or Rollback,
+// which run the callbacks according to the result.
+func (tx *Tx) end() {
+	tx.mu.Lock()
+	tx.ending = true
+	tx.mu.Unlock()
+	if tx.stop != nil {
+		tx.stop()
+	}
+}
+
+// autoRollback rolls the transaction back when its context is done
+// and runs the OnRollback callbacks. database/sql rolls it back then
+// as well, but asynchronously; whichever is first, the other one
+// gets sql.ErrTxDone. A Commit started before that either succeeds
+// or fails and runs the callbacks itself.
+func (tx *Tx) autoRollback() {
+	tx.mu.Lock()
+	ending := tx.ending
+	tx.mu.Unlock()
+	if !ending {
+		tx.Tx.Rollback()
+		tx.runCallbacks(false)
+	}
+}
+
+// runCallbacks runs the OnCommit or OnRollback callbacks
+// and forgets all of them, so they are run at most once.
+func (tx *Tx) runCallbacks(committed bool) {
+	tx.mu.Lock()
+	hooks := tx.onRollback
+	if committed {
+		hooks = tx.onCommit
+	}
+	tx.onCommit, tx.onRollback = nil, nil
+	tx.mu.Unlock()
+	for _, f := range hooks {
+		f()
+	}
+}
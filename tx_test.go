@@ -1,6 +1,15 @@
 package dali
 
-import "testing"
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mibk/dali/internal/testdriver"
+)
 
 var transactionTests = []struct {
 	sql      string
@@ -39,3 +48,117 @@ func TestTransactions(t *testing.T) {
 		}
 	}
 }
+
+func TestQuerier(t *testing.T) {
+	find := func(q Querier, id int) string {
+		return q.Query("SELECT [name] FROM [user] WHERE [id] = ?", id).query
+	}
+	tx, _ := db.Begin()
+	const want = "SELECT {name} FROM {user} WHERE {id} = 7"
+	for _, q := range []Querier{db, tx} {
+		if got := find(q, 7); got != want {
+			t.Errorf("%T:\n got: %v\nwant: %v", q, got, want)
+		}
+	}
+}
+
+func TestContextTx(t *testing.T) {
+	db := NewDB(db.DB, dvr)
+	tx, _ := db.Begin()
+	ctx := WithTx(context.Background(), tx)
+
+	if q := db.QueryWithContext(ctx, "SELECT 1"); q.execer != tx.Tx {
+		t.Errorf("query is not routed to the transaction in ctx")
+	}
+	if q := db.QueryWithContext(context.Background(), "SELECT 1"); q.execer != db.DB {
+		t.Errorf("query is routed to a transaction without one in ctx")
+	}
+	if _, err := db.PrepareContext(ctx, "SELECT ?"); err != nil {
+		t.Errorf("prepare within ctx transaction: %v", err)
+	}
+}
+
+func TestTxHooks(t *testing.T) {
+	var calls []string
+	hook := func(name string) func() {
+		return func() { calls = append(calls, name) }
+	}
+
+	tx := db.mustBegin()
+	tx.OnCommit(hook("commit1"))
+	tx.OnCommit(hook("commit2"))
+	tx.OnRollback(hook("rollback"))
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"commit1", "commit2"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("commit: got %v, want %v", calls, want)
+	}
+
+	calls = nil
+	tx = db.mustBegin()
+	tx.OnCommit(hook("commit"))
+	tx.OnRollback(hook("rollback"))
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"rollback"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("rollback: got %v, want %v", calls, want)
+	}
+
+	calls = nil
+	if err := tx.Rollback(); err == nil {
+		t.Errorf("second rollback: an error was expected but none given")
+	}
+	if len(calls) > 0 {
+		t.Errorf("hooks called after a failed rollback: %v", calls)
+	}
+}
+
+func TestTxRollbackCallbacks(t *testing.T) {
+	drv, handle := testdriver.New()
+	db := NewDB(handle, dvr)
+	var calls []string
+	hook := func(name string) func() {
+		return func() { calls = append(calls, name) }
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.OnCommit(hook("commit"))
+	tx.OnRollback(hook("rollback"))
+	drv.FailNext(errors.New("commit failed"))
+	if err := tx.Commit(); err == nil {
+		t.Fatal("commit: an error was expected but none given")
+	}
+	if want := []string{"rollback"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("failed commit: got %v, want %v", calls, want)
+	}
+
+	calls = nil
+	ctx, cancel := context.WithCancel(context.Background())
+	tx, err = db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rolledBack := make(chan struct{})
+	tx.OnCommit(hook("commit"))
+	tx.OnRollback(func() { close(rolledBack) })
+	cancel()
+	select {
+	case <-rolledBack:
+	case <-time.After(time.Second):
+		t.Fatal("canceled context: rollback callback not called")
+	}
+	if err := tx.Rollback(); err != sql.ErrTxDone {
+		t.Errorf("rollback after cancel: got %v, want %v", err, sql.ErrTxDone)
+	}
+	if err := tx.Commit(); err == nil {
+		t.Error("commit after cancel: an error was expected but none given")
+	}
+	if len(calls) > 0 {
+		t.Errorf("callbacks called after cancel: %v", calls)
+	}
+}